@@ -0,0 +1,101 @@
+// Command awsbi-reaper removes AWS resources left behind by awsbi environment
+// which could not be destroyed by the module itself.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/reaper"
+)
+
+func main() {
+	var config reaper.Config
+	var janitorConfig reaper.JanitorConfig
+	var janitor bool
+	var allowList string
+
+	flag.StringVar(&config.GroupName, "group", "", "name of resource group created by module, e.g. epiphany-rg")
+	flag.StringVar(&config.TagKey, "tag-key", "resource_group", "key of tag which marks environment resources")
+	flag.StringVar(&config.TagValue, "tag-value", "", "value of tag which marks environment resources, usually module name")
+	flag.StringVar(&config.KeyPairPrefix, "key-pair-prefix", "", "name prefix of key pairs to remove, in addition to tagged ones (default <tag-value>-kp)")
+	flag.StringVar(&config.Region, "region", "eu-central-1", "AWS region of environment")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "only list resources which would be deleted")
+	flag.IntVar(&config.Workers, "workers", reaper.DefaultWorkers, "number of resources removed at the same time")
+	flag.Float64Var(&config.RequestsPerSecond, "requests-per-second", reaper.DefaultRequestsPerSecond, "limit of AWS API calls per second")
+	flag.DurationVar(&config.TerminationTimeout, "termination-timeout", reaper.DefaultTerminationTimeout, "time of waiting for termination of single instance or nat gateway")
+	flag.BoolVar(&janitor, "janitor", false, "remove all environments older than -ttl instead of single environment")
+	flag.DurationVar(&janitorConfig.TTL, "ttl", 0, "janitor: age after which environment is removed, e.g. 72h")
+	flag.StringVar(&allowList, "allow", "", "janitor: comma separated names (or patterns) of environments which are never removed")
+	flag.StringVar(&janitorConfig.CreatedTagKey, "created-tag", reaper.DefaultCreatedTagKey, "janitor: key of tag holding creation time of environment (RFC 3339), launch time of instances is used when missing")
+	flag.Parse()
+
+	if janitor {
+		janitorConfig.TagKey = config.TagKey
+		janitorConfig.Region = config.Region
+		janitorConfig.DryRun = config.DryRun
+		janitorConfig.Workers = config.Workers
+		janitorConfig.RequestsPerSecond = config.RequestsPerSecond
+		janitorConfig.TerminationTimeout = config.TerminationTimeout
+		if allowList != "" {
+			for _, name := range strings.Split(allowList, ",") {
+				janitorConfig.AllowList = append(janitorConfig.AllowList, strings.TrimSpace(name))
+			}
+		}
+		sweep(janitorConfig)
+		return
+	}
+
+	if config.GroupName == "" && config.TagValue != "" {
+		config.GroupName = config.TagValue + "-rg"
+	}
+
+	r, err := reaper.New(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "awsbi-reaper:", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	report, err := r.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if config.DryRun {
+		fmt.Println("Discovered:")
+		report.Print(os.Stdout)
+		return
+	}
+
+	// resources are not discovered again to verify cleanup, tagging API keeps returning terminated
+	// and just deleted resources for a while, failures of removal are collected in report
+	fmt.Println("Summary:")
+	report.Print(os.Stdout)
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
+
+// removes all environments older than TTL
+func sweep(config reaper.JanitorConfig) {
+	j, err := reaper.NewJanitor(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "awsbi-reaper:", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	report, err := j.Sweep()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Environments:")
+	report.Print(os.Stdout)
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
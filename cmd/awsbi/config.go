@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/config"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/tfvars"
+)
+
+// renderConfig writes config file built from -input config file and M_* environment variables while
+// holding lock of config file and stores it in history, config file is left as it is when any of them
+// is invalid
+func renderConfig(o options) error {
+	var data []byte
+	var err error
+	name := o.input
+	switch o.input {
+	case "":
+	case "-":
+		name = "<stdin>"
+		data, err = stdin()
+	default:
+		data, err = ioutil.ReadFile(o.input)
+	}
+	if err != nil {
+		return err
+	}
+	rendered, err := config.Render(name, data, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	l, err := acquire(o, o.config)
+	if err != nil {
+		return err
+	}
+	defer release(l)
+
+	if err := state.WriteFileAtomic(o.config, rendered); err != nil {
+		return err
+	}
+	takeSnapshot(o)
+	return nil
+}
+
+// renderTfvars writes terraform variables file with module parameters from config file, config file
+// is locked so it is not changed while it is rendered
+func renderTfvars(o options) error {
+	l, err := acquire(o, o.config)
+	if err != nil {
+		return err
+	}
+	defer release(l)
+
+	c, err := state.LoadConfig(o.config)
+	if err != nil {
+		return err
+	}
+	variables, err := tfvars.LoadVariables(o.variables)
+	if err != nil {
+		return err
+	}
+	data, err := tfvars.Render(*c, variables)
+	if err != nil {
+		return err
+	}
+	return state.WriteFileAtomic(o.tfvars, data)
+}
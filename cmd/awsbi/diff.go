@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/diff"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+// errChanges is returned by diff when there are changes, main exits with code 2 then
+var errChanges = errors.New("there are changes")
+
+func planDiff(o options) error {
+	f, err := state.Load(o.state)
+	if err != nil {
+		return err
+	}
+	before, err := f.ConfigValues()
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		before = map[string]interface{}{}
+	}
+	after, err := state.LoadConfigValues(o.config)
+	if err != nil {
+		return err
+	}
+	if after == nil {
+		after = map[string]interface{}{}
+	}
+
+	changes := diff.Compare(state.ModuleKey, before, after)
+
+	if o.jsonOut != "" {
+		var buffer bytes.Buffer
+		if err := diff.JSON(&buffer, changes); err != nil {
+			return err
+		}
+		if err := state.WriteFileAtomic(o.jsonOut, buffer.Bytes()); err != nil {
+			return err
+		}
+	}
+	if o.json {
+		err = diff.JSON(os.Stdout, changes)
+	} else {
+		err = diff.Text(os.Stdout, changes)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		return errChanges
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/history"
+)
+
+func snapshot(o options) error {
+	h, err := openHistory(o)
+	if err != nil {
+		return err
+	}
+	s, err := h.Snapshot("awsbi "+o.name, o.version)
+	if err != nil {
+		return err
+	}
+	if s != nil {
+		fmt.Println("Stored snapshot", s.Serial)
+	}
+	return nil
+}
+
+func listHistory(o options) error {
+	h, err := openHistory(o)
+	if err != nil {
+		return err
+	}
+	snapshots, err := h.List()
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("There are no snapshots.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SERIAL\tCREATED\tCOMMAND\tVERSION\tFILES")
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%v\n", s.Serial, s.Created.Format("2006-01-02 15:04:05"), s.Command, s.Version, s.Files)
+	}
+	return w.Flush()
+}
+
+// rollback restores snapshot while holding locks of all restored files
+func rollback(o options) error {
+	if len(o.args) != 1 {
+		return fmt.Errorf("usage: awsbi rollback <serial>")
+	}
+	serial, err := strconv.Atoi(o.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid serial %q", o.args[0])
+	}
+	h, err := openHistory(o)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range []string{o.state, o.config, o.tfstate} {
+		l, err := acquire(o, path)
+		if err != nil {
+			return err
+		}
+		defer release(l)
+	}
+
+	s, err := h.Restore(serial)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Restored snapshot", s.Serial, "taken by", s.Command, "at", s.Created.Format("2006-01-02 15:04:05"))
+	o.name += " " + o.args[0]
+	takeSnapshot(o)
+	return nil
+}
+
+// takes snapshot after files were changed, failure is only reported as the change is already done
+func takeSnapshot(o options) {
+	h, err := openHistory(o)
+	if err == nil {
+		_, err = h.Snapshot("awsbi "+o.name, o.version)
+	}
+	if err != nil {
+		log.Println("awsbi: cannot store snapshot in history:", err)
+	}
+}
+
+func openHistory(o options) (*history.History, error) {
+	h, err := history.New(o.history, o.state, o.config, o.tfstate)
+	if err != nil {
+		return nil, err
+	}
+	h.LockWait = o.lockTimeout
+	return h, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/lock"
+)
+
+// withLock runs command while holding lock of file, lock is refreshed while command runs
+func withLock(o options) error {
+	if o.lock == "" || len(o.args) == 0 {
+		return fmt.Errorf("usage: awsbi with-lock -lock=<path> -- <command> [args]")
+	}
+	l, err := acquire(o, o.lock)
+	if err != nil {
+		return err
+	}
+	defer release(l)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(lock.DefaultTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := l.Refresh(); err != nil {
+					log.Println("awsbi with-lock:", err)
+				}
+			}
+		}
+	}()
+
+	cmd := exec.Command(o.args[0], o.args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if o.snapshot {
+		// failed command could have changed files as well
+		takeSnapshot(o)
+	}
+	return err
+}
+
+func forceUnlock(o options) error {
+	if o.lock == "" {
+		return fmt.Errorf("usage: awsbi force-unlock -lock=<path>")
+	}
+	info, err := lock.ForceUnlock(o.lock)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		fmt.Println(o.lock, "is not locked")
+		return nil
+	}
+	fmt.Println("Removed lock of", o.lock, "held by", info)
+	return nil
+}
+
+// acquires lock of file at path on behalf of command
+func acquire(o options, path string) (*lock.Lock, error) {
+	return lock.Acquire(path, lock.Options{Owner: "awsbi " + o.name, Wait: o.lockTimeout})
+}
+
+// releases lock, failure is only reported as the work is already done
+func release(l *lock.Lock) {
+	if err := l.Release(); err != nil {
+		log.Println("awsbi:", err)
+	}
+}
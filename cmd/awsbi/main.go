@@ -0,0 +1,148 @@
+// Command awsbi implements steps of module workflow executed by Makefile in module container,
+// like updates of shared state file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+)
+
+// command is single step of module workflow
+type command struct {
+	usage string
+	run   func(o options) error
+}
+
+var commands = map[string]command{
+	"init-state":           {"marks module as initialized in state file", initState},
+	"start-apply":          {"marks module as being applied in state file", startApply},
+	"update-after-apply":   {"copies module config to state file and marks module as applied", updateAfterApply},
+	"start-destroy":        {"marks module as being destroyed in state file", startDestroy},
+	"update-after-destroy": {"removes module config and outputs from state file and marks module as destroyed", updateAfterDestroy},
+	"mark-failed":          {"marks module as failed after interrupted apply or destroy", markFailed},
+	"assert-init-allowed":  {"checks that module status allows init, before config file is changed", assertInitAllowed},
+	"assert-initialized":   {"checks that module is initialized and has config file", assertInitialized},
+	"set-output":           {"stores terraform outputs (`terraform output -json` read from stdin) in state file", setOutput},
+	"render-config":        {"writes module config file built from -input config file and M_* environment variables, reports every invalid value", renderConfig},
+	"preflight":            {"prints network layout (subnets, zones, NAT gateways) of module parameters, fails when it is impossible", preflight},
+	"render-tfvars":        {"writes terraform variables file with module parameters from config file, checks them against terraform variables", renderTfvars},
+	"validate-config":      {"checks module config file against schema, reports position of every problem", validateConfig},
+	"validate-state":       {"checks structure of state file and dependencies declared in module metadata", validateState},
+	"with-lock":            {"runs command given after -- while holding lock of -lock file", withLock},
+	"force-unlock":         {"removes lock of -lock file regardless of its owner", forceUnlock},
+	"snapshot":             {"stores current state, config and terraform state files in history", snapshot},
+	"history":              {"lists snapshots stored in history", listHistory},
+	"rollback":             {"restores files from snapshot with serial given as argument", rollback},
+	"migrate":              {"upgrades state and config files written by older module versions to current schema version", migrate},
+	"terraform":            {"runs plan, plan-destroy, apply or destroy given as argument with terraform, -json prints result (changes, outputs, duration, errors)", runTerraform},
+	"policy-check":         {"checks plan against -policy rules (world-open ingress, public IPs, root volumes, regions, OS, tags) and prints violations, -enforce fails on errors", policyCheck},
+	"plan-summary":         {"prints changes of resources by type from plan JSON (`terraform show -json`) given as argument or read from stdin", planSummary},
+	"diff":                 {"compares module parameters in state file with config file, exits with 0 when there are no changes, 2 when there are changes and 1 on error", planDiff},
+}
+
+func main() {
+	// usage errors exit with 1 like other errors, exit code 2 is reserved for changes found by diff and plan
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintln(os.Stderr, "awsbi: unknown command", name)
+		usage()
+		os.Exit(1)
+	}
+	o, err := parse(name, os.Args[2:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		// flag package has already reported the problem
+		os.Exit(1)
+	}
+	if err := cmd.run(o); err != nil {
+		if err == errChanges {
+			os.Exit(2)
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			// command run by with-lock has already reported its problem
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintln(os.Stderr, "awsbi "+name+":", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: awsbi <command> [flags]\n\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-22s %s\n", name, commands[name].usage)
+	}
+}
+
+// options are flags of commands, every command uses only some of them
+type options struct {
+	name        string
+	args        []string
+	state       string
+	config      string
+	metadata    string
+	version     string
+	json        bool
+	jsonOut     string
+	lock        string
+	lockTimeout time.Duration
+	tfstate     string
+	history     string
+	snapshot    bool
+	input       string
+	variables   string
+	tfvars      string
+	zones       string
+	dir         string
+	policy      string
+	enforce     bool
+}
+
+// parses flags following command name
+func parse(name string, args []string) (options, error) {
+	o := options{name: name}
+	set := flag.NewFlagSet("awsbi "+name, flag.ContinueOnError)
+	set.StringVar(&o.state, "state", "/shared/state.yml", "path of shared state file")
+	set.StringVar(&o.config, "config", "/shared/awsbi/awsbi-config.yml", "path of module config file")
+	set.StringVar(&o.metadata, "metadata", "-", "path of module metadata (output of `make metadata`), - reads stdin")
+	set.StringVar(&o.version, "version", os.Getenv("M_VERSION"), "version of module recorded with changes of status")
+	set.BoolVar(&o.json, "json", false, "diff, terraform, plan-summary, policy-check: print changes, result, summary or report as JSON instead of text")
+	set.StringVar(&o.jsonOut, "json-out", "", "diff, terraform, policy-check: also write changes, result or report as JSON to file")
+	set.StringVar(&o.lock, "lock", "", "with-lock, force-unlock: path of locked file")
+	set.DurationVar(&o.lockTimeout, "lock-timeout", 30*time.Second, "how long to wait for lock held by another module run")
+	set.StringVar(&o.tfstate, "tfstate", "/shared/awsbi/terraform.tfstate", "path of terraform state file")
+	set.StringVar(&o.history, "history", "/shared/awsbi/history", "path of directory with snapshots of state, config and terraform state files")
+	set.BoolVar(&o.snapshot, "snapshot", false, "with-lock: take snapshot of files after command finishes")
+	set.StringVar(&o.variables, "variables", "/resources/terraform/variables.tf", "render-tfvars: path of terraform file declaring variables")
+	set.StringVar(&o.tfvars, "tfvars", "/shared/awsbi/vars.tfvars.json", "render-tfvars, terraform: path of terraform variables file")
+	set.StringVar(&o.zones, "zones", "", "preflight: comma separated availability zones of region, read from AWS when empty")
+	set.StringVar(&o.dir, "dir", "/resources/terraform", "terraform, policy-check: directory with terraform files of module")
+	set.StringVar(&o.policy, "policy", "", "policy-check: path of YAML or JSON policy file, default policy is used when empty")
+	set.BoolVar(&o.enforce, "enforce", false, "policy-check: fail when plan violates rule with error severity")
+	set.StringVar(&o.input, "input", "", "render-config: path of YAML or JSON config file merged with M_* variables, - reads stdin")
+	err := set.Parse(args)
+	o.args = set.Args()
+	return o, err
+}
+
+// stdin returns content of standard input
+func stdin() ([]byte, error) {
+	return ioutil.ReadAll(os.Stdin)
+}
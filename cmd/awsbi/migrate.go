@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/migration"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+// migrate upgrades state and config files written by older module versions, files which are
+// already current are left untouched
+func migrate(o options) error {
+	for _, path := range []string{o.state, o.config} {
+		l, err := acquire(o, path)
+		if err != nil {
+			return err
+		}
+		defer release(l)
+	}
+
+	f, err := state.Load(o.state)
+	if err != nil {
+		return err
+	}
+	stateResult, err := migration.MigrateState(f)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(o.config)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot read config file %s: %w", o.config, err)
+	}
+	var configResult migration.Result
+	if err == nil {
+		if data, configResult, err = migration.MigrateConfig(data); err != nil {
+			return fmt.Errorf("%s: %w", o.config, err)
+		}
+	}
+
+	if !stateResult.Changed() && !configResult.Changed() {
+		return nil
+	}
+	// files written by older module version could have never been stored in history
+	before := o
+	before.name += " (before)"
+	takeSnapshot(before)
+
+	// both files are checked before any of them is written, so failure leaves them consistent
+	if stateResult.Changed() {
+		if err := f.Save(); err != nil {
+			return err
+		}
+		report(o.state, stateResult)
+	}
+	if configResult.Changed() {
+		if err := state.WriteFileAtomic(o.config, data); err != nil {
+			return err
+		}
+		report(o.config, configResult)
+	}
+	takeSnapshot(o)
+	return nil
+}
+
+func report(path string, result migration.Result) {
+	fmt.Printf("Migrated %s from schema version %d to %d:\n", path, result.From, result.To)
+	for _, step := range result.Steps {
+		fmt.Printf("  %d -> %d: %s\n", step.From, step.From+1, step.Description)
+	}
+}
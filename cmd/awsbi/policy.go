@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/policy"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/terraform"
+)
+
+// policyCheck checks plan created by `awsbi terraform plan` against rules of -policy file and
+// prints violations, with -enforce it fails when any rule with error severity is violated
+func policyCheck(o options) error {
+	p, err := policy.Load(o.policy)
+	if err != nil {
+		return err
+	}
+	tf := &terraform.Terraform{Dir: o.dir, Stderr: os.Stderr}
+	plan, err := tf.Show(context.Background(), filepath.Join(filepath.Dir(o.tfstate), "terraform-apply.tfplan"))
+	if err != nil {
+		return err
+	}
+	report, err := p.Evaluate(plan)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if o.jsonOut != "" {
+		if err := state.WriteFileAtomic(o.jsonOut, data); err != nil {
+			return err
+		}
+	}
+	if o.json {
+		os.Stdout.Write(data)
+	} else {
+		report.Print(os.Stdout)
+	}
+	if o.enforce && report.Blocking() {
+		return fmt.Errorf("plan violates %d policy rules with error severity, it cannot be applied", report.Count(policy.Error))
+	}
+	return nil
+}
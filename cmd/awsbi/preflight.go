@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/network"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+// preflight prints network layout of module parameters and fails when terraform cannot create it
+func preflight(o options) error {
+	c, err := state.LoadConfig(o.config)
+	if err != nil {
+		return err
+	}
+	var zones []string
+	if o.zones != "" {
+		zones = strings.Split(o.zones, ",")
+	} else {
+		newSession, err := session.NewSession(&aws.Config{Region: aws.String(c.Region)})
+		if err != nil {
+			return fmt.Errorf("cannot get session: %w", err)
+		}
+		if zones, err = network.Zones(ec2.New(newSession)); err != nil {
+			return err
+		}
+	}
+
+	layout, err := network.Compute(*c, zones)
+	if layout != nil {
+		fmt.Print(layout.Text())
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+func initState(o options) error {
+	return updateState(o, func(f *state.File) error {
+		return f.Initialize(o.version)
+	})
+}
+
+// assertInitAllowed checks that init may change module status, so refused init does not change
+// config file
+func assertInitAllowed(o options) error {
+	f, err := state.Load(o.state)
+	if err != nil {
+		return err
+	}
+	status, err := f.Status()
+	if err != nil {
+		return err
+	}
+	return state.CheckTransition(status, state.Initialized)
+}
+
+func startApply(o options) error {
+	return updateState(o, func(f *state.File) error {
+		return f.StartApply(o.version)
+	})
+}
+
+func updateAfterApply(o options) error {
+	config, err := state.LoadConfig(o.config)
+	if err != nil {
+		return err
+	}
+	return updateState(o, func(f *state.File) error {
+		return f.Applied(*config, o.version)
+	})
+}
+
+func startDestroy(o options) error {
+	return updateState(o, func(f *state.File) error {
+		return f.StartDestroy(o.version)
+	})
+}
+
+func updateAfterDestroy(o options) error {
+	return updateState(o, func(f *state.File) error {
+		return f.Destroyed(o.version)
+	})
+}
+
+func markFailed(o options) error {
+	return updateState(o, func(f *state.File) error {
+		return f.Fail(o.version)
+	})
+}
+
+func assertInitialized(o options) error {
+	f, err := state.Load(o.state)
+	if err != nil {
+		return err
+	}
+	status, err := f.Status()
+	if err != nil {
+		return err
+	}
+	if status == "" || status == state.Destroyed {
+		return fmt.Errorf("module is not initialized, run init first")
+	}
+	if _, err := os.Stat(o.config); err != nil {
+		return fmt.Errorf("module config file is missing, run init first: %w", err)
+	}
+	return nil
+}
+
+func setOutput(o options) error {
+	data, err := stdin()
+	if err != nil {
+		return err
+	}
+	output, err := state.ParseTerraformOutput(data)
+	if err != nil {
+		return err
+	}
+	return updateState(o, func(f *state.File) error {
+		return f.SetOutput(output)
+	})
+}
+
+// loads state file, applies update and saves it while holding lock of the file
+func updateState(o options, update func(f *state.File) error) error {
+	l, err := acquire(o, o.state)
+	if err != nil {
+		return err
+	}
+	defer release(l)
+
+	f, err := state.Load(o.state)
+	if err != nil {
+		return err
+	}
+	if err := update(f); err != nil {
+		return err
+	}
+	if err := f.Save(); err != nil {
+		return err
+	}
+	takeSnapshot(o)
+	return nil
+}
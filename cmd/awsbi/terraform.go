@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/terraform"
+)
+
+// runTerraform runs lifecycle operation given as argument with terraform and reports its result,
+// plans are kept next to terraform state
+func runTerraform(o options) error {
+	if len(o.args) != 1 {
+		return fmt.Errorf("usage: awsbi terraform <plan|plan-destroy|apply|destroy>")
+	}
+	tf := terraform.New(o.dir)
+	tf.Stdout, tf.Stderr = os.Stdout, os.Stderr
+	if o.json {
+		// stdout is left for result
+		tf.Stdout = os.Stderr
+	}
+	dir := filepath.Dir(o.tfstate)
+	applyPlan := filepath.Join(dir, "terraform-apply.tfplan")
+	destroyPlan := filepath.Join(dir, "terraform-destroy.tfplan")
+
+	ctx := context.Background()
+	var result *terraform.Result
+	var err error
+	switch terraform.Operation(o.args[0]) {
+	case terraform.Plan:
+		result, err = tf.Plan(ctx, terraform.PlanOptions{VarFile: o.tfvars, State: o.tfstate, Out: applyPlan})
+	case terraform.PlanDestroy:
+		result, err = tf.Plan(ctx, terraform.PlanOptions{VarFile: o.tfvars, State: o.tfstate, Out: destroyPlan, Destroy: true})
+	case terraform.Apply:
+		result, err = tf.Apply(ctx, terraform.ApplyOptions{State: o.tfstate, Plan: applyPlan})
+	case terraform.Destroy:
+		result, err = tf.Apply(ctx, terraform.ApplyOptions{State: o.tfstate, Plan: destroyPlan, Destroy: true})
+	default:
+		return fmt.Errorf("unknown terraform operation %q", o.args[0])
+	}
+
+	data, marshalErr := json.MarshalIndent(result, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+	data = append(data, '\n')
+	if o.jsonOut != "" {
+		if writeErr := state.WriteFileAtomic(o.jsonOut, data); writeErr != nil {
+			return writeErr
+		}
+	}
+	if o.json {
+		os.Stdout.Write(data)
+	} else if err == nil && (result.Operation == terraform.Plan || result.Operation == terraform.PlanDestroy) {
+		fmt.Print("\nResources by type:\n", result.Summary.Text())
+	}
+	return err
+}
+
+// planSummary prints changes of resources by type from plan in JSON format (output of
+// `terraform show -json`) read from file given as argument or stdin
+func planSummary(o options) error {
+	if len(o.args) > 1 {
+		return fmt.Errorf("usage: awsbi plan-summary [plan.json]")
+	}
+	var data []byte
+	var err error
+	if len(o.args) == 0 || o.args[0] == "-" {
+		data, err = stdin()
+	} else {
+		data, err = ioutil.ReadFile(o.args[0])
+	}
+	if err != nil {
+		return err
+	}
+	resources, err := terraform.ResourceChanges(data)
+	if err != nil {
+		return err
+	}
+	summary := terraform.Summarize(resources)
+	if o.json {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(summary.Text())
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/config"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/metadata"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+func validateConfig(o options) error {
+	return config.Validate(o.config)
+}
+
+func validateState(o options) error {
+	var data []byte
+	var err error
+	if o.metadata == "-" {
+		data, err = stdin()
+	} else {
+		data, err = ioutil.ReadFile(o.metadata)
+	}
+	if err != nil {
+		return err
+	}
+	m, err := metadata.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	f, err := state.Load(o.state)
+	if err != nil {
+		return err
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	return f.CheckDependencies(m.Dependencies.Strong, m.Dependencies.Weak)
+}
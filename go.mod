@@ -3,7 +3,9 @@ module github.com/epiphany-platform/aws-basic-infrastructure
 go 1.15
 
 require (
-	github.com/aws/aws-sdk-go v1.15.77
-	github.com/stretchr/testify v1.6.1 // indirect
+	github.com/aws/aws-sdk-go v1.35.37
+	github.com/hashicorp/hcl/v2 v2.8.2
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.0
 	golang.org/x/crypto v0.0.0-20201002170205-7f63de1d35b0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,1220 @@
+// Package awsfake provides in-memory fakes of AWS API clients, so code using them
+// (e.g. reaper) can be tested without AWS account. Fakes keep state of resources,
+// mimic dependency errors returned by AWS and allow to inject errors for single calls.
+package awsfake
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// NewError creates AWS error with given code, as returned by AWS API
+func NewError(code string) error {
+	return awserr.New(code, "fake "+code, nil)
+}
+
+// failures holds errors injected for calls of operation on resource
+type failures struct {
+	errs  map[string][]error
+	calls []string
+}
+
+// FailOn makes next calls of operation (e.g. "DeleteSubnet") on resource with given ID return errs in order
+func (f *failures) FailOn(operation, id string, errs ...error) {
+	if f.errs == nil {
+		f.errs = make(map[string][]error)
+	}
+	key := operation + " " + id
+	f.errs[key] = append(f.errs[key], errs...)
+}
+
+// Calls returns all recorded calls in form "Operation id" in order in which they were made
+func (f *failures) Calls() []string {
+	calls := make([]string, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+// records call and returns injected error if there is one
+func (f *failures) call(operation, id string) error {
+	key := operation + " " + id
+	f.calls = append(f.calls, key)
+	if errs := f.errs[key]; len(errs) > 0 {
+		f.errs[key] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
+
+// EC2 is in-memory fake of EC2 API. Only methods used by this repository are implemented,
+// calling any other method panics.
+type EC2 struct {
+	ec2iface.EC2API
+	failures
+
+	// PageSize limits number of resources returned in single page of paginated calls, 0 means no limit
+	PageSize int
+	// StuckShuttingDown makes terminated instances stay in shutting-down state
+	StuckShuttingDown bool
+	// StuckDeleting makes deleted nat gateways stay in deleting state
+	StuckDeleting bool
+
+	mutex            sync.Mutex
+	instances        map[string]*ec2.Instance
+	securityGroups   map[string]*ec2.SecurityGroup
+	natGateways      map[string]*ec2.NatGateway
+	addresses        map[string]*ec2.Address
+	internetGateways map[string]*ec2.InternetGateway
+	routeTables      map[string]*ec2.RouteTable
+	subnets          map[string]*ec2.Subnet
+	vpcs             map[string]*ec2.Vpc
+	keyPairs         map[string]*ec2.KeyPairInfo
+	interfaces       map[string]*ec2.NetworkInterface
+	volumes          map[string]*ec2.Volume
+}
+
+// NewEC2 creates fake EC2 without any resources
+func NewEC2() *EC2 {
+	return &EC2{
+		instances:        make(map[string]*ec2.Instance),
+		securityGroups:   make(map[string]*ec2.SecurityGroup),
+		natGateways:      make(map[string]*ec2.NatGateway),
+		addresses:        make(map[string]*ec2.Address),
+		internetGateways: make(map[string]*ec2.InternetGateway),
+		routeTables:      make(map[string]*ec2.RouteTable),
+		subnets:          make(map[string]*ec2.Subnet),
+		vpcs:             make(map[string]*ec2.Vpc),
+		keyPairs:         make(map[string]*ec2.KeyPairInfo),
+		interfaces:       make(map[string]*ec2.NetworkInterface),
+		volumes:          make(map[string]*ec2.Volume),
+	}
+}
+
+// FailOn makes next calls of operation (e.g. "DeleteSubnet") on resource with given ID return errs in order
+func (f *EC2) FailOn(operation, id string, errs ...error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failures.FailOn(operation, id, errs...)
+}
+
+// Calls returns all recorded calls in form "Operation id" in order in which they were made
+func (f *EC2) Calls() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.failures.Calls()
+}
+
+// AddInstance adds instance, instance without state is running
+func (f *EC2) AddInstance(instance *ec2.Instance) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if instance.State == nil {
+		instance.State = &ec2.InstanceState{Code: aws.Int64(16), Name: aws.String(ec2.InstanceStateNameRunning)}
+	}
+	f.instances[*instance.InstanceId] = instance
+}
+
+// AddSecurityGroup adds security group
+func (f *EC2) AddSecurityGroup(sg *ec2.SecurityGroup) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.securityGroups[*sg.GroupId] = sg
+}
+
+// AddNatGateway adds nat gateway and associates its elastic IPs, nat gateway without state is available
+func (f *EC2) AddNatGateway(ng *ec2.NatGateway) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if ng.State == nil {
+		ng.State = aws.String(ec2.NatGatewayStateAvailable)
+	}
+	for _, address := range ng.NatGatewayAddresses {
+		if eip, ok := f.addresses[aws.StringValue(address.AllocationId)]; ok {
+			eip.AssociationId = aws.String("eipassoc-" + *ng.NatGatewayId)
+		}
+	}
+	f.natGateways[*ng.NatGatewayId] = ng
+}
+
+// AddAddress adds elastic IP
+func (f *EC2) AddAddress(address *ec2.Address) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.addresses[*address.AllocationId] = address
+}
+
+// AddInternetGateway adds internet gateway
+func (f *EC2) AddInternetGateway(igw *ec2.InternetGateway) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.internetGateways[*igw.InternetGatewayId] = igw
+}
+
+// AddRouteTable adds route table
+func (f *EC2) AddRouteTable(rt *ec2.RouteTable) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.routeTables[*rt.RouteTableId] = rt
+}
+
+// AddSubnet adds subnet
+func (f *EC2) AddSubnet(subnet *ec2.Subnet) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.subnets[*subnet.SubnetId] = subnet
+}
+
+// AddVpc adds VPC
+func (f *EC2) AddVpc(vpc *ec2.Vpc) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.vpcs[*vpc.VpcId] = vpc
+}
+
+// AddKeyPair adds key pair
+func (f *EC2) AddKeyPair(kp *ec2.KeyPairInfo) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.keyPairs[*kp.KeyName] = kp
+}
+
+// AddNetworkInterface adds network interface, network interface without status is available
+func (f *EC2) AddNetworkInterface(eni *ec2.NetworkInterface) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if eni.Status == nil {
+		eni.Status = aws.String(ec2.NetworkInterfaceStatusAvailable)
+	}
+	f.interfaces[*eni.NetworkInterfaceId] = eni
+}
+
+// AddVolume adds volume, volume without state is available
+func (f *EC2) AddVolume(volume *ec2.Volume) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if volume.State == nil {
+		volume.State = aws.String(ec2.VolumeStateAvailable)
+	}
+	f.volumes[*volume.VolumeId] = volume
+}
+
+// SetLaunchTime sets launch time of instance
+func (f *EC2) SetLaunchTime(id string, launchTime time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.instances[id].LaunchTime = aws.Time(launchTime)
+}
+
+// Tag adds tags to resource with given ID (or key pair name), like CreateTags does
+func (f *EC2) Tag(id string, tags ...*ec2.Tag) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch {
+	case f.instances[id] != nil:
+		f.instances[id].Tags = append(f.instances[id].Tags, tags...)
+	case f.securityGroups[id] != nil:
+		f.securityGroups[id].Tags = append(f.securityGroups[id].Tags, tags...)
+	case f.natGateways[id] != nil:
+		f.natGateways[id].Tags = append(f.natGateways[id].Tags, tags...)
+	case f.addresses[id] != nil:
+		f.addresses[id].Tags = append(f.addresses[id].Tags, tags...)
+	case f.internetGateways[id] != nil:
+		f.internetGateways[id].Tags = append(f.internetGateways[id].Tags, tags...)
+	case f.routeTables[id] != nil:
+		f.routeTables[id].Tags = append(f.routeTables[id].Tags, tags...)
+	case f.subnets[id] != nil:
+		f.subnets[id].Tags = append(f.subnets[id].Tags, tags...)
+	case f.vpcs[id] != nil:
+		f.vpcs[id].Tags = append(f.vpcs[id].Tags, tags...)
+	case f.keyPairs[id] != nil:
+		f.keyPairs[id].Tags = append(f.keyPairs[id].Tags, tags...)
+	case f.interfaces[id] != nil:
+		f.interfaces[id].TagSet = append(f.interfaces[id].TagSet, tags...)
+	case f.volumes[id] != nil:
+		f.volumes[id].Tags = append(f.volumes[id].Tags, tags...)
+	default:
+		panic("awsfake: cannot tag not existing resource " + id)
+	}
+}
+
+// Exists checks if resource with given ID (or key pair name) still exists, terminated instances
+// and deleted nat gateways are treated as not existing
+func (f *EC2) Exists(id string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if instance, ok := f.instances[id]; ok {
+		return *instance.State.Name != ec2.InstanceStateNameTerminated
+	}
+	if ng, ok := f.natGateways[id]; ok {
+		return *ng.State != ec2.NatGatewayStateDeleted
+	}
+	_, sg := f.securityGroups[id]
+	_, eip := f.addresses[id]
+	_, igw := f.internetGateways[id]
+	_, rt := f.routeTables[id]
+	_, subnet := f.subnets[id]
+	_, vpc := f.vpcs[id]
+	_, kp := f.keyPairs[id]
+	_, eni := f.interfaces[id]
+	_, volume := f.volumes[id]
+	return sg || eip || igw || rt || subnet || vpc || kp || eni || volume
+}
+
+// returns page boundaries for n elements split into pages of given size, 0 means single page
+func pages(size, n int) [][2]int {
+	if n == 0 {
+		return [][2]int{{0, 0}}
+	}
+	if size <= 0 || size > n {
+		size = n
+	}
+	result := make([][2]int, 0)
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		result = append(result, [2]int{start, end})
+	}
+	return result
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+	switch typed := m.(type) {
+	case map[string]*ec2.Instance:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.SecurityGroup:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.NatGateway:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.Address:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.InternetGateway:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.RouteTable:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.Subnet:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.Vpc:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.KeyPairInfo:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.NetworkInterface:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	case map[string]*ec2.Volume:
+		for k := range typed {
+			keys = append(keys, k)
+		}
+	default:
+		panic(fmt.Sprintf("awsfake: unsupported map %T", m))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// --- Instances ---
+
+func (f *EC2) describeInstances(input *ec2.DescribeInstancesInput) ([]*ec2.Instance, error) {
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		if _, ok := f.instances[id]; !ok {
+			return nil, NewError("InvalidInstanceID.NotFound")
+		}
+	}
+	result := make([]*ec2.Instance, 0)
+	for _, id := range sortedKeys(f.instances) {
+		instance := f.instances[id]
+		attributes := map[string]string{
+			"instance-id":         id,
+			"instance-state-name": aws.StringValue(instance.State.Name),
+			"subnet-id":           aws.StringValue(instance.SubnetId),
+			"vpc-id":              aws.StringValue(instance.VpcId),
+		}
+		if containsID(input.InstanceIds, id) && matches(input.Filters, attributes, instance.Tags) {
+			result = append(result, awsutil.CopyOf(instance).(*ec2.Instance))
+		}
+	}
+	return result, nil
+}
+
+// DescribeInstances returns all matching instances in single reservation
+func (f *EC2) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.call("DescribeInstances", ""); err != nil {
+		return nil, err
+	}
+	instances, err := f.describeInstances(input)
+	if err != nil {
+		return nil, err
+	}
+	output := &ec2.DescribeInstancesOutput{}
+	if len(instances) > 0 {
+		output.Reservations = []*ec2.Reservation{{Instances: instances}}
+	}
+	return output, nil
+}
+
+// DescribeInstancesPages returns matching instances in pages of PageSize reservations
+func (f *EC2) DescribeInstancesPages(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+	f.mutex.Lock()
+	if err := f.call("DescribeInstances", ""); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	instances, err := f.describeInstances(input)
+	pages := pages(f.PageSize, len(instances))
+	f.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	for i, page := range pages {
+		output := &ec2.DescribeInstancesOutput{}
+		for _, instance := range instances[page[0]:page[1]] {
+			output.Reservations = append(output.Reservations, &ec2.Reservation{Instances: []*ec2.Instance{instance}})
+		}
+		if !fn(output, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// TerminateInstances marks instances as terminated, network interfaces and volumes attached to terminated instances
+// are deleted or detached, depending on their delete on termination flag
+func (f *EC2) TerminateInstances(input *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	output := &ec2.TerminateInstancesOutput{}
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		if err := f.call("TerminateInstances", id); err != nil {
+			return nil, err
+		}
+		instance, ok := f.instances[id]
+		if !ok {
+			return nil, NewError("InvalidInstanceID.NotFound")
+		}
+		previous := awsutil.CopyOf(instance.State).(*ec2.InstanceState)
+		instance.State = &ec2.InstanceState{Code: aws.Int64(48), Name: aws.String(ec2.InstanceStateNameTerminated)}
+		if f.StuckShuttingDown {
+			instance.State = &ec2.InstanceState{Code: aws.Int64(32), Name: aws.String(ec2.InstanceStateNameShuttingDown)}
+		} else {
+			f.detach(id)
+		}
+		output.TerminatingInstances = append(output.TerminatingInstances, &ec2.InstanceStateChange{
+			InstanceId:    aws.String(id),
+			PreviousState: previous,
+			CurrentState:  awsutil.CopyOf(instance.State).(*ec2.InstanceState),
+		})
+	}
+	return output, nil
+}
+
+// detach deletes or detaches network interfaces and volumes attached to instance
+func (f *EC2) detach(instanceID string) {
+	for id, eni := range f.interfaces {
+		if eni.Attachment == nil || aws.StringValue(eni.Attachment.InstanceId) != instanceID {
+			continue
+		}
+		if aws.BoolValue(eni.Attachment.DeleteOnTermination) {
+			delete(f.interfaces, id)
+			continue
+		}
+		eni.Attachment = nil
+		eni.Status = aws.String(ec2.NetworkInterfaceStatusAvailable)
+	}
+	for id, volume := range f.volumes {
+		if len(volume.Attachments) == 0 || aws.StringValue(volume.Attachments[0].InstanceId) != instanceID {
+			continue
+		}
+		if aws.BoolValue(volume.Attachments[0].DeleteOnTermination) {
+			delete(f.volumes, id)
+			continue
+		}
+		volume.Attachments = nil
+		volume.State = aws.String(ec2.VolumeStateAvailable)
+	}
+}
+
+// WaitUntilInstanceTerminated returns ResourceNotReady error if any of instances is not terminated
+func (f *EC2) WaitUntilInstanceTerminated(input *ec2.DescribeInstancesInput) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.call("WaitUntilInstanceTerminated", ""); err != nil {
+		return err
+	}
+	instances, err := f.describeInstances(input)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if *instance.State.Name != ec2.InstanceStateNameTerminated {
+			return NewError(request.WaiterResourceNotReadyErrorCode)
+		}
+	}
+	return nil
+}
+
+// WaitUntilInstanceTerminatedWithContext returns immediately if all instances are terminated, otherwise
+// it waits until context is done and returns error like the real waiter does
+func (f *EC2) WaitUntilInstanceTerminatedWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.WaiterOption) error {
+	f.mutex.Lock()
+	if err := f.call("WaitUntilInstanceTerminated", aws.StringValue(firstID(input.InstanceIds))); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	instances, err := f.describeInstances(input)
+	f.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if *instance.State.Name != ec2.InstanceStateNameTerminated {
+			<-ctx.Done()
+			return awserr.New(request.CanceledErrorCode, "waiter context canceled", ctx.Err())
+		}
+	}
+	return nil
+}
+
+// --- Security groups ---
+
+// DescribeSecurityGroupsPages returns matching security groups in pages
+func (f *EC2) DescribeSecurityGroupsPages(input *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) error {
+	f.mutex.Lock()
+	if err := f.call("DescribeSecurityGroups", ""); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	result := make([]*ec2.SecurityGroup, 0)
+	for _, id := range sortedKeys(f.securityGroups) {
+		sg := f.securityGroups[id]
+		attributes := map[string]string{"group-id": id, "group-name": aws.StringValue(sg.GroupName), "vpc-id": aws.StringValue(sg.VpcId)}
+		if containsID(input.GroupIds, id) && matches(input.Filters, attributes, sg.Tags) {
+			result = append(result, awsutil.CopyOf(sg).(*ec2.SecurityGroup))
+		}
+	}
+	pages := pages(f.PageSize, len(result))
+	f.mutex.Unlock()
+	for i, page := range pages {
+		if !fn(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: result[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteSecurityGroup deletes security group which is not used by any instance
+func (f *EC2) DeleteSecurityGroup(input *ec2.DeleteSecurityGroupInput) (*ec2.DeleteSecurityGroupOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.GroupId)
+	if err := f.call("DeleteSecurityGroup", id); err != nil {
+		return nil, err
+	}
+	if _, ok := f.securityGroups[id]; !ok {
+		return nil, NewError("InvalidGroup.NotFound")
+	}
+	for _, instance := range f.instances {
+		if *instance.State.Name == ec2.InstanceStateNameTerminated {
+			continue
+		}
+		for _, sg := range instance.SecurityGroups {
+			if aws.StringValue(sg.GroupId) == id {
+				return nil, NewError("DependencyViolation")
+			}
+		}
+	}
+	for _, eni := range f.interfaces {
+		for _, sg := range eni.Groups {
+			if aws.StringValue(sg.GroupId) == id {
+				return nil, NewError("DependencyViolation")
+			}
+		}
+	}
+	delete(f.securityGroups, id)
+	return &ec2.DeleteSecurityGroupOutput{}, nil
+}
+
+// --- Nat gateways ---
+
+func (f *EC2) describeNatGateways(input *ec2.DescribeNatGatewaysInput) ([]*ec2.NatGateway, error) {
+	for _, id := range aws.StringValueSlice(input.NatGatewayIds) {
+		if _, ok := f.natGateways[id]; !ok {
+			return nil, NewError("NatGatewayNotFound")
+		}
+	}
+	result := make([]*ec2.NatGateway, 0)
+	for _, id := range sortedKeys(f.natGateways) {
+		ng := f.natGateways[id]
+		attributes := map[string]string{
+			"nat-gateway-id": id,
+			"state":          aws.StringValue(ng.State),
+			"subnet-id":      aws.StringValue(ng.SubnetId),
+			"vpc-id":         aws.StringValue(ng.VpcId),
+		}
+		if containsID(input.NatGatewayIds, id) && matches(input.Filter, attributes, ng.Tags) {
+			result = append(result, awsutil.CopyOf(ng).(*ec2.NatGateway))
+		}
+	}
+	return result, nil
+}
+
+// DescribeNatGateways returns matching nat gateways, including deleted ones
+func (f *EC2) DescribeNatGateways(input *ec2.DescribeNatGatewaysInput) (*ec2.DescribeNatGatewaysOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.call("DescribeNatGateways", aws.StringValue(firstID(input.NatGatewayIds))); err != nil {
+		return nil, err
+	}
+	ngs, err := f.describeNatGateways(input)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeNatGatewaysOutput{NatGateways: ngs}, nil
+}
+
+// DescribeNatGatewaysPages returns matching nat gateways in pages
+func (f *EC2) DescribeNatGatewaysPages(input *ec2.DescribeNatGatewaysInput, fn func(*ec2.DescribeNatGatewaysOutput, bool) bool) error {
+	f.mutex.Lock()
+	if err := f.call("DescribeNatGateways", aws.StringValue(firstID(input.NatGatewayIds))); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	ngs, err := f.describeNatGateways(input)
+	pages := pages(f.PageSize, len(ngs))
+	f.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	for i, page := range pages {
+		if !fn(&ec2.DescribeNatGatewaysOutput{NatGateways: ngs[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteNatGateway marks nat gateway as deleted and disassociates its elastic IPs, unless StuckDeleting is set
+func (f *EC2) DeleteNatGateway(input *ec2.DeleteNatGatewayInput) (*ec2.DeleteNatGatewayOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.NatGatewayId)
+	if err := f.call("DeleteNatGateway", id); err != nil {
+		return nil, err
+	}
+	ng, ok := f.natGateways[id]
+	if !ok {
+		return nil, NewError("NatGatewayNotFound")
+	}
+	if f.StuckDeleting {
+		ng.State = aws.String(ec2.NatGatewayStateDeleting)
+		return &ec2.DeleteNatGatewayOutput{NatGatewayId: aws.String(id)}, nil
+	}
+	ng.State = aws.String(ec2.NatGatewayStateDeleted)
+	for _, address := range ng.NatGatewayAddresses {
+		if eip, ok := f.addresses[aws.StringValue(address.AllocationId)]; ok {
+			eip.AssociationId = nil
+		}
+	}
+	return &ec2.DeleteNatGatewayOutput{NatGatewayId: aws.String(id)}, nil
+}
+
+// --- Addresses ---
+
+// DescribeAddresses returns matching elastic IPs
+func (f *EC2) DescribeAddresses(input *ec2.DescribeAddressesInput) (*ec2.DescribeAddressesOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.call("DescribeAddresses", ""); err != nil {
+		return nil, err
+	}
+	output := &ec2.DescribeAddressesOutput{}
+	for _, id := range sortedKeys(f.addresses) {
+		address := f.addresses[id]
+		attributes := map[string]string{"allocation-id": id, "domain": "vpc"}
+		if containsID(input.AllocationIds, id) && matches(input.Filters, attributes, address.Tags) {
+			output.Addresses = append(output.Addresses, awsutil.CopyOf(address).(*ec2.Address))
+		}
+	}
+	return output, nil
+}
+
+// ReleaseAddress releases elastic IP which is not associated
+func (f *EC2) ReleaseAddress(input *ec2.ReleaseAddressInput) (*ec2.ReleaseAddressOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.AllocationId)
+	if err := f.call("ReleaseAddress", id); err != nil {
+		return nil, err
+	}
+	address, ok := f.addresses[id]
+	if !ok {
+		return nil, NewError("InvalidAllocationID.NotFound")
+	}
+	if address.AssociationId != nil {
+		return nil, NewError("InvalidIPAddress.InUse")
+	}
+	delete(f.addresses, id)
+	return &ec2.ReleaseAddressOutput{}, nil
+}
+
+// --- Internet gateways ---
+
+func (f *EC2) describeInternetGateways(input *ec2.DescribeInternetGatewaysInput) ([]*ec2.InternetGateway, error) {
+	for _, id := range aws.StringValueSlice(input.InternetGatewayIds) {
+		if _, ok := f.internetGateways[id]; !ok {
+			return nil, NewError("InvalidInternetGatewayID.NotFound")
+		}
+	}
+	result := make([]*ec2.InternetGateway, 0)
+	for _, id := range sortedKeys(f.internetGateways) {
+		igw := f.internetGateways[id]
+		attributes := map[string]string{"internet-gateway-id": id}
+		if len(igw.Attachments) > 0 {
+			attributes["attachment.vpc-id"] = aws.StringValue(igw.Attachments[0].VpcId)
+		}
+		if containsID(input.InternetGatewayIds, id) && matches(input.Filters, attributes, igw.Tags) {
+			result = append(result, awsutil.CopyOf(igw).(*ec2.InternetGateway))
+		}
+	}
+	return result, nil
+}
+
+// DescribeInternetGateways returns matching internet gateways
+func (f *EC2) DescribeInternetGateways(input *ec2.DescribeInternetGatewaysInput) (*ec2.DescribeInternetGatewaysOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.call("DescribeInternetGateways", aws.StringValue(firstID(input.InternetGatewayIds))); err != nil {
+		return nil, err
+	}
+	igws, err := f.describeInternetGateways(input)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeInternetGatewaysOutput{InternetGateways: igws}, nil
+}
+
+// DescribeInternetGatewaysPages returns matching internet gateways in pages
+func (f *EC2) DescribeInternetGatewaysPages(input *ec2.DescribeInternetGatewaysInput, fn func(*ec2.DescribeInternetGatewaysOutput, bool) bool) error {
+	f.mutex.Lock()
+	if err := f.call("DescribeInternetGateways", aws.StringValue(firstID(input.InternetGatewayIds))); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	igws, err := f.describeInternetGateways(input)
+	pages := pages(f.PageSize, len(igws))
+	f.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	for i, page := range pages {
+		if !fn(&ec2.DescribeInternetGatewaysOutput{InternetGateways: igws[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// DetachInternetGateway detaches internet gateway from VPC which has no mapped public addresses
+func (f *EC2) DetachInternetGateway(input *ec2.DetachInternetGatewayInput) (*ec2.DetachInternetGatewayOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.InternetGatewayId)
+	vpcID := aws.StringValue(input.VpcId)
+	if err := f.call("DetachInternetGateway", id); err != nil {
+		return nil, err
+	}
+	igw, ok := f.internetGateways[id]
+	if !ok {
+		return nil, NewError("InvalidInternetGatewayID.NotFound")
+	}
+	attachments := make([]*ec2.InternetGatewayAttachment, 0)
+	for _, attachment := range igw.Attachments {
+		if aws.StringValue(attachment.VpcId) != vpcID {
+			attachments = append(attachments, attachment)
+		}
+	}
+	if len(attachments) == len(igw.Attachments) {
+		return nil, NewError("Gateway.NotAttached")
+	}
+	for _, instance := range f.instances {
+		if aws.StringValue(instance.VpcId) == vpcID && instance.PublicIpAddress != nil && *instance.State.Name != ec2.InstanceStateNameTerminated {
+			return nil, NewError("DependencyViolation")
+		}
+	}
+	for _, ng := range f.natGateways {
+		if aws.StringValue(ng.VpcId) == vpcID && *ng.State != ec2.NatGatewayStateDeleted {
+			return nil, NewError("DependencyViolation")
+		}
+	}
+	igw.Attachments = attachments
+	return &ec2.DetachInternetGatewayOutput{}, nil
+}
+
+// DeleteInternetGateway deletes detached internet gateway
+func (f *EC2) DeleteInternetGateway(input *ec2.DeleteInternetGatewayInput) (*ec2.DeleteInternetGatewayOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.InternetGatewayId)
+	if err := f.call("DeleteInternetGateway", id); err != nil {
+		return nil, err
+	}
+	igw, ok := f.internetGateways[id]
+	if !ok {
+		return nil, NewError("InvalidInternetGatewayID.NotFound")
+	}
+	if len(igw.Attachments) > 0 {
+		return nil, NewError("DependencyViolation")
+	}
+	delete(f.internetGateways, id)
+	return &ec2.DeleteInternetGatewayOutput{}, nil
+}
+
+// --- Route tables ---
+
+// DescribeRouteTables returns matching route tables
+func (f *EC2) DescribeRouteTables(input *ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+	output := &ec2.DescribeRouteTablesOutput{}
+	err := f.DescribeRouteTablesPages(input, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		output.RouteTables = append(output.RouteTables, page.RouteTables...)
+		return true
+	})
+	return output, err
+}
+
+// DescribeRouteTablesPages returns matching route tables in pages
+func (f *EC2) DescribeRouteTablesPages(input *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool) error {
+	f.mutex.Lock()
+	if err := f.call("DescribeRouteTables", aws.StringValue(firstID(input.RouteTableIds))); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	result := make([]*ec2.RouteTable, 0)
+	for _, id := range sortedKeys(f.routeTables) {
+		rt := f.routeTables[id]
+		attributes := map[string]string{"route-table-id": id, "vpc-id": aws.StringValue(rt.VpcId)}
+		if containsID(input.RouteTableIds, id) && matches(input.Filters, attributes, rt.Tags) {
+			result = append(result, awsutil.CopyOf(rt).(*ec2.RouteTable))
+		}
+	}
+	pages := pages(f.PageSize, len(result))
+	f.mutex.Unlock()
+	for i, page := range pages {
+		if !fn(&ec2.DescribeRouteTablesOutput{RouteTables: result[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// DisassociateRouteTable removes subnet association of route table
+func (f *EC2) DisassociateRouteTable(input *ec2.DisassociateRouteTableInput) (*ec2.DisassociateRouteTableOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.AssociationId)
+	if err := f.call("DisassociateRouteTable", id); err != nil {
+		return nil, err
+	}
+	for _, rt := range f.routeTables {
+		for i, association := range rt.Associations {
+			if aws.StringValue(association.RouteTableAssociationId) == id {
+				if aws.BoolValue(association.Main) {
+					return nil, NewError("InvalidParameterValue")
+				}
+				rt.Associations = append(rt.Associations[:i], rt.Associations[i+1:]...)
+				return &ec2.DisassociateRouteTableOutput{}, nil
+			}
+		}
+	}
+	return nil, NewError("InvalidAssociationID.NotFound")
+}
+
+// DeleteRouteTable deletes route table without associations
+func (f *EC2) DeleteRouteTable(input *ec2.DeleteRouteTableInput) (*ec2.DeleteRouteTableOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.RouteTableId)
+	if err := f.call("DeleteRouteTable", id); err != nil {
+		return nil, err
+	}
+	rt, ok := f.routeTables[id]
+	if !ok {
+		return nil, NewError("InvalidRouteTableID.NotFound")
+	}
+	if len(rt.Associations) > 0 {
+		return nil, NewError("DependencyViolation")
+	}
+	delete(f.routeTables, id)
+	return &ec2.DeleteRouteTableOutput{}, nil
+}
+
+// --- Subnets ---
+
+// DescribeSubnetsPages returns matching subnets in pages
+func (f *EC2) DescribeSubnetsPages(input *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool) error {
+	f.mutex.Lock()
+	if err := f.call("DescribeSubnets", ""); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	result := make([]*ec2.Subnet, 0)
+	for _, id := range sortedKeys(f.subnets) {
+		subnet := f.subnets[id]
+		attributes := map[string]string{"subnet-id": id, "vpc-id": aws.StringValue(subnet.VpcId)}
+		if containsID(input.SubnetIds, id) && matches(input.Filters, attributes, subnet.Tags) {
+			result = append(result, awsutil.CopyOf(subnet).(*ec2.Subnet))
+		}
+	}
+	pages := pages(f.PageSize, len(result))
+	f.mutex.Unlock()
+	for i, page := range pages {
+		if !fn(&ec2.DescribeSubnetsOutput{Subnets: result[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteSubnet deletes subnet without running instances and nat gateways, route table associations are removed
+func (f *EC2) DeleteSubnet(input *ec2.DeleteSubnetInput) (*ec2.DeleteSubnetOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.SubnetId)
+	if err := f.call("DeleteSubnet", id); err != nil {
+		return nil, err
+	}
+	if _, ok := f.subnets[id]; !ok {
+		return nil, NewError("InvalidSubnetID.NotFound")
+	}
+	for _, instance := range f.instances {
+		if aws.StringValue(instance.SubnetId) == id && *instance.State.Name != ec2.InstanceStateNameTerminated {
+			return nil, NewError("DependencyViolation")
+		}
+	}
+	for _, ng := range f.natGateways {
+		if aws.StringValue(ng.SubnetId) == id && *ng.State != ec2.NatGatewayStateDeleted {
+			return nil, NewError("DependencyViolation")
+		}
+	}
+	for _, eni := range f.interfaces {
+		if aws.StringValue(eni.SubnetId) == id {
+			return nil, NewError("DependencyViolation")
+		}
+	}
+	for _, rt := range f.routeTables {
+		associations := make([]*ec2.RouteTableAssociation, 0)
+		for _, association := range rt.Associations {
+			if aws.StringValue(association.SubnetId) != id {
+				associations = append(associations, association)
+			}
+		}
+		rt.Associations = associations
+	}
+	delete(f.subnets, id)
+	return &ec2.DeleteSubnetOutput{}, nil
+}
+
+// --- VPCs ---
+
+// DeleteVpc deletes VPC without subnets, security groups, attached internet gateways and route tables
+func (f *EC2) DeleteVpc(input *ec2.DeleteVpcInput) (*ec2.DeleteVpcOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.VpcId)
+	if err := f.call("DeleteVpc", id); err != nil {
+		return nil, err
+	}
+	if _, ok := f.vpcs[id]; !ok {
+		return nil, NewError("InvalidVpcID.NotFound")
+	}
+	for _, subnet := range f.subnets {
+		if aws.StringValue(subnet.VpcId) == id {
+			return nil, NewError("DependencyViolation")
+		}
+	}
+	for _, sg := range f.securityGroups {
+		if aws.StringValue(sg.VpcId) == id && aws.StringValue(sg.GroupName) != "default" {
+			return nil, NewError("DependencyViolation")
+		}
+	}
+	for _, igw := range f.internetGateways {
+		for _, attachment := range igw.Attachments {
+			if aws.StringValue(attachment.VpcId) == id {
+				return nil, NewError("DependencyViolation")
+			}
+		}
+	}
+	for _, rt := range f.routeTables {
+		if aws.StringValue(rt.VpcId) == id && !isMain(rt) {
+			return nil, NewError("DependencyViolation")
+		}
+	}
+	// default security group and main route table are removed together with VPC
+	for sgID, sg := range f.securityGroups {
+		if aws.StringValue(sg.VpcId) == id {
+			delete(f.securityGroups, sgID)
+		}
+	}
+	for rtID, rt := range f.routeTables {
+		if aws.StringValue(rt.VpcId) == id {
+			delete(f.routeTables, rtID)
+		}
+	}
+	delete(f.vpcs, id)
+	return &ec2.DeleteVpcOutput{}, nil
+}
+
+// --- Network interfaces ---
+
+// DescribeNetworkInterfacesPages returns matching network interfaces in pages
+func (f *EC2) DescribeNetworkInterfacesPages(input *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool) error {
+	f.mutex.Lock()
+	if err := f.call("DescribeNetworkInterfaces", ""); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	result := make([]*ec2.NetworkInterface, 0)
+	for _, id := range sortedKeys(f.interfaces) {
+		eni := f.interfaces[id]
+		attributes := map[string]string{
+			"network-interface-id": id,
+			"status":               aws.StringValue(eni.Status),
+			"subnet-id":            aws.StringValue(eni.SubnetId),
+			"vpc-id":               aws.StringValue(eni.VpcId),
+		}
+		if eni.Attachment != nil {
+			attributes["attachment.instance-id"] = aws.StringValue(eni.Attachment.InstanceId)
+			attributes["attachment.delete-on-termination"] = fmt.Sprint(aws.BoolValue(eni.Attachment.DeleteOnTermination))
+		}
+		if containsID(input.NetworkInterfaceIds, id) && matches(input.Filters, attributes, eni.TagSet) {
+			result = append(result, awsutil.CopyOf(eni).(*ec2.NetworkInterface))
+		}
+	}
+	pages := pages(f.PageSize, len(result))
+	f.mutex.Unlock()
+	for i, page := range pages {
+		if !fn(&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: result[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteNetworkInterface deletes detached network interface
+func (f *EC2) DeleteNetworkInterface(input *ec2.DeleteNetworkInterfaceInput) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.NetworkInterfaceId)
+	if err := f.call("DeleteNetworkInterface", id); err != nil {
+		return nil, err
+	}
+	eni, ok := f.interfaces[id]
+	if !ok {
+		return nil, NewError("InvalidNetworkInterfaceID.NotFound")
+	}
+	if aws.StringValue(eni.Status) != ec2.NetworkInterfaceStatusAvailable {
+		return nil, NewError("InvalidNetworkInterface.InUse")
+	}
+	delete(f.interfaces, id)
+	return &ec2.DeleteNetworkInterfaceOutput{}, nil
+}
+
+// --- Volumes ---
+
+// DescribeVolumesPages returns matching volumes in pages
+func (f *EC2) DescribeVolumesPages(input *ec2.DescribeVolumesInput, fn func(*ec2.DescribeVolumesOutput, bool) bool) error {
+	f.mutex.Lock()
+	if err := f.call("DescribeVolumes", ""); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	result := make([]*ec2.Volume, 0)
+	for _, id := range sortedKeys(f.volumes) {
+		volume := f.volumes[id]
+		attributes := map[string]string{"volume-id": id, "status": aws.StringValue(volume.State)}
+		if len(volume.Attachments) > 0 {
+			attributes["attachment.instance-id"] = aws.StringValue(volume.Attachments[0].InstanceId)
+			attributes["attachment.delete-on-termination"] = fmt.Sprint(aws.BoolValue(volume.Attachments[0].DeleteOnTermination))
+		}
+		if containsID(input.VolumeIds, id) && matches(input.Filters, attributes, volume.Tags) {
+			result = append(result, awsutil.CopyOf(volume).(*ec2.Volume))
+		}
+	}
+	pages := pages(f.PageSize, len(result))
+	f.mutex.Unlock()
+	for i, page := range pages {
+		if !fn(&ec2.DescribeVolumesOutput{Volumes: result[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteVolume deletes unattached volume
+func (f *EC2) DeleteVolume(input *ec2.DeleteVolumeInput) (*ec2.DeleteVolumeOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := aws.StringValue(input.VolumeId)
+	if err := f.call("DeleteVolume", id); err != nil {
+		return nil, err
+	}
+	volume, ok := f.volumes[id]
+	if !ok {
+		return nil, NewError("InvalidVolume.NotFound")
+	}
+	if aws.StringValue(volume.State) != ec2.VolumeStateAvailable {
+		return nil, NewError("VolumeInUse")
+	}
+	delete(f.volumes, id)
+	return &ec2.DeleteVolumeOutput{}, nil
+}
+
+// --- Key pairs ---
+
+// DescribeKeyPairs returns matching key pairs
+func (f *EC2) DescribeKeyPairs(input *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.call("DescribeKeyPairs", ""); err != nil {
+		return nil, err
+	}
+	output := &ec2.DescribeKeyPairsOutput{}
+	for _, name := range sortedKeys(f.keyPairs) {
+		kp := f.keyPairs[name]
+		attributes := map[string]string{"key-name": name, "key-pair-id": aws.StringValue(kp.KeyPairId)}
+		if containsID(input.KeyNames, name) && matches(input.Filters, attributes, kp.Tags) {
+			output.KeyPairs = append(output.KeyPairs, awsutil.CopyOf(kp).(*ec2.KeyPairInfo))
+		}
+	}
+	return output, nil
+}
+
+// DeleteKeyPair deletes key pair, deleting not existing key pair succeeds like in AWS
+func (f *EC2) DeleteKeyPair(input *ec2.DeleteKeyPairInput) (*ec2.DeleteKeyPairOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	name := aws.StringValue(input.KeyName)
+	if err := f.call("DeleteKeyPair", name); err != nil {
+		return nil, err
+	}
+	delete(f.keyPairs, name)
+	return &ec2.DeleteKeyPairOutput{}, nil
+}
+
+func copyTags(tags []*ec2.Tag) []*ec2.Tag {
+	result := make([]*ec2.Tag, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, &ec2.Tag{Key: aws.String(aws.StringValue(tag.Key)), Value: aws.String(aws.StringValue(tag.Value))})
+	}
+	return result
+}
+
+func isMain(rt *ec2.RouteTable) bool {
+	for _, association := range rt.Associations {
+		if aws.BoolValue(association.Main) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstID(ids []*string) *string {
+	if len(ids) == 0 {
+		return aws.String("")
+	}
+	return ids[0]
+}
+
+// region and account used in ARNs of fake resources
+const (
+	Region  = "eu-central-1"
+	Account = "123456789012"
+)
+
+// taggedResource is resource as returned by resource groups and tagging APIs
+type taggedResource struct {
+	arn          string
+	resourceType string
+	tags         []*ec2.Tag
+}
+
+// returns existing resources tagged with given tag key and one of values (any value if there are none), sorted by ARN
+func (f *EC2) tagged(key string, values []string) []taggedResource {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	result := make([]taggedResource, 0)
+	add := func(arnType, resourceType, id string, tags []*ec2.Tag) {
+		actual, ok := tagValue(tags, key)
+		if !ok || (len(values) > 0 && !matchesAny(aws.StringSlice(values), actual)) {
+			return
+		}
+		result = append(result, taggedResource{
+			arn:          fmt.Sprintf("arn:aws:ec2:%s:%s:%s/%s", Region, Account, arnType, id),
+			resourceType: "AWS::EC2::" + resourceType,
+			tags:         copyTags(tags),
+		})
+	}
+	for id, instance := range f.instances {
+		if *instance.State.Name != ec2.InstanceStateNameTerminated {
+			add("instance", "Instance", id, instance.Tags)
+		}
+	}
+	for id, sg := range f.securityGroups {
+		add("security-group", "SecurityGroup", id, sg.Tags)
+	}
+	for id, ng := range f.natGateways {
+		if *ng.State != ec2.NatGatewayStateDeleted {
+			add("natgateway", "NatGateway", id, ng.Tags)
+		}
+	}
+	for id, address := range f.addresses {
+		add("elastic-ip", "EIP", id, address.Tags)
+	}
+	for id, igw := range f.internetGateways {
+		add("internet-gateway", "InternetGateway", id, igw.Tags)
+	}
+	for id, rt := range f.routeTables {
+		add("route-table", "RouteTable", id, rt.Tags)
+	}
+	for id, subnet := range f.subnets {
+		add("subnet", "Subnet", id, subnet.Tags)
+	}
+	for id, vpc := range f.vpcs {
+		add("vpc", "VPC", id, vpc.Tags)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].arn < result[j].arn
+	})
+	return result
+}
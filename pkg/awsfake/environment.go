@@ -0,0 +1,113 @@
+package awsfake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Environment holds IDs of resources created by AddEnvironment
+type Environment struct {
+	Vpc             string
+	InternetGateway string
+	PublicSubnet    string
+	PrivateSubnet   string
+	PublicRT        string
+	PrivateRT       string
+	MainRT          string
+	Address         string
+	NatGateway      string
+	SecurityGroup   string
+	Instance        string
+	KeyPair         string
+}
+
+// AddEnvironment adds to fake EC2 resources created by module for environment with given name,
+// tagged like module does it. IDs of resources are derived from name, so many environments
+// can be added to single fake.
+func AddEnvironment(f *EC2, tagKey, name string) Environment {
+	env := Environment{
+		Vpc:             "vpc-" + name,
+		InternetGateway: "igw-" + name,
+		PublicSubnet:    "subnet-" + name + "-public",
+		PrivateSubnet:   "subnet-" + name + "-private",
+		PublicRT:        "rtb-" + name + "-public",
+		PrivateRT:       "rtb-" + name + "-private",
+		MainRT:          "rtb-" + name + "-main",
+		Address:         "eipalloc-" + name,
+		NatGateway:      "nat-" + name,
+		SecurityGroup:   "sg-" + name,
+		Instance:        "i-" + name,
+		KeyPair:         name + "-kp",
+	}
+	tags := func() []*ec2.Tag {
+		return Tags(tagKey, name)
+	}
+
+	f.AddVpc(&ec2.Vpc{VpcId: aws.String(env.Vpc), Tags: tags()})
+	f.AddRouteTable(&ec2.RouteTable{
+		RouteTableId: aws.String(env.MainRT),
+		VpcId:        aws.String(env.Vpc),
+		Associations: []*ec2.RouteTableAssociation{{
+			RouteTableAssociationId: aws.String("rtbassoc-" + name + "-main"),
+			RouteTableId:            aws.String(env.MainRT),
+			Main:                    aws.Bool(true),
+		}},
+	})
+	f.AddSecurityGroup(&ec2.SecurityGroup{
+		GroupId:   aws.String("sg-" + name + "-default"),
+		GroupName: aws.String("default"),
+		VpcId:     aws.String(env.Vpc),
+	})
+	f.AddInternetGateway(&ec2.InternetGateway{
+		InternetGatewayId: aws.String(env.InternetGateway),
+		Attachments:       []*ec2.InternetGatewayAttachment{{VpcId: aws.String(env.Vpc), State: aws.String("available")}},
+		Tags:              tags(),
+	})
+	f.AddSubnet(&ec2.Subnet{SubnetId: aws.String(env.PublicSubnet), VpcId: aws.String(env.Vpc), Tags: tags()})
+	f.AddSubnet(&ec2.Subnet{SubnetId: aws.String(env.PrivateSubnet), VpcId: aws.String(env.Vpc), Tags: tags()})
+	for _, rt := range []struct{ id, subnet string }{{env.PublicRT, env.PublicSubnet}, {env.PrivateRT, env.PrivateSubnet}} {
+		f.AddRouteTable(&ec2.RouteTable{
+			RouteTableId: aws.String(rt.id),
+			VpcId:        aws.String(env.Vpc),
+			Associations: []*ec2.RouteTableAssociation{{
+				RouteTableAssociationId: aws.String("rtbassoc-" + rt.subnet),
+				RouteTableId:            aws.String(rt.id),
+				SubnetId:                aws.String(rt.subnet),
+				Main:                    aws.Bool(false),
+			}},
+			Tags: tags(),
+		})
+	}
+	f.AddAddress(&ec2.Address{AllocationId: aws.String(env.Address), Domain: aws.String("vpc"), Tags: tags()})
+	f.AddNatGateway(&ec2.NatGateway{
+		NatGatewayId:        aws.String(env.NatGateway),
+		SubnetId:            aws.String(env.PublicSubnet),
+		VpcId:               aws.String(env.Vpc),
+		NatGatewayAddresses: []*ec2.NatGatewayAddress{{AllocationId: aws.String(env.Address)}},
+		Tags:                tags(),
+	})
+	f.AddSecurityGroup(&ec2.SecurityGroup{
+		GroupId:   aws.String(env.SecurityGroup),
+		GroupName: aws.String(name + "-sg"),
+		VpcId:     aws.String(env.Vpc),
+		Tags:      tags(),
+	})
+	f.AddInstance(&ec2.Instance{
+		InstanceId:      aws.String(env.Instance),
+		SubnetId:        aws.String(env.PublicSubnet),
+		VpcId:           aws.String(env.Vpc),
+		PublicIpAddress: aws.String("203.0.113.10"),
+		SecurityGroups:  []*ec2.GroupIdentifier{{GroupId: aws.String(env.SecurityGroup)}},
+		Tags:            tags(),
+	})
+	f.AddKeyPair(&ec2.KeyPairInfo{KeyName: aws.String(env.KeyPair), KeyPairId: aws.String("key-" + name), Tags: tags()})
+	return env
+}
+
+// IDs returns IDs of all tagged resources of environment
+func (e Environment) IDs() []string {
+	return []string{
+		e.Instance, e.SecurityGroup, e.NatGateway, e.Address, e.InternetGateway,
+		e.PublicRT, e.PrivateRT, e.PublicSubnet, e.PrivateSubnet, e.Vpc, e.KeyPair,
+	}
+}
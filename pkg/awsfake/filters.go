@@ -0,0 +1,87 @@
+package awsfake
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// matches checks if resource with given attributes and tags passes all describe filters.
+// Filters are combined with AND, values of single filter with OR, value ending with '*' matches prefix.
+// Filters not known for resource never match, as AWS rejects unknown filter names. Filter tag-key matches
+// resources having any of tag keys.
+func matches(filters []*ec2.Filter, attributes map[string]string, tags []*ec2.Tag) bool {
+	for _, filter := range filters {
+		name := aws.StringValue(filter.Name)
+		var actual string
+		var ok bool
+		if name == "tag-key" {
+			if !hasAnyTagKey(tags, filter.Values) {
+				return false
+			}
+			continue
+		}
+		if strings.HasPrefix(name, "tag:") {
+			actual, ok = tagValue(tags, strings.TrimPrefix(name, "tag:"))
+		} else {
+			actual, ok = attributes[name]
+		}
+		if !ok || !matchesAny(filter.Values, actual) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(values []*string, actual string) bool {
+	for _, value := range aws.StringValueSlice(values) {
+		if strings.HasSuffix(value, "*") && strings.HasPrefix(actual, strings.TrimSuffix(value, "*")) {
+			return true
+		}
+		if value == actual {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyTagKey(tags []*ec2.Tag, keys []*string) bool {
+	for _, tag := range tags {
+		if matchesAny(keys, aws.StringValue(tag.Key)) {
+			return true
+		}
+	}
+	return false
+}
+
+func tagValue(tags []*ec2.Tag, key string) (string, bool) {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value), true
+		}
+	}
+	return "", false
+}
+
+// containsID checks if id is on the list, empty list matches every id
+func containsID(ids []*string, id string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, candidate := range ids {
+		if aws.StringValue(candidate) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags creates EC2 tags from key value pairs
+func Tags(keyValues ...string) []*ec2.Tag {
+	tags := make([]*ec2.Tag, 0, len(keyValues)/2)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		tags = append(tags, &ec2.Tag{Key: aws.String(keyValues[i]), Value: aws.String(keyValues[i+1])})
+	}
+	return tags
+}
@@ -0,0 +1,161 @@
+package awsfake
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/aws/aws-sdk-go/service/resourcegroups/resourcegroupsiface"
+)
+
+// ResourceGroups is in-memory fake of Resource Groups API. Groups are tag based, resources
+// are taken from fake EC2. Only methods used by this repository are implemented.
+type ResourceGroups struct {
+	resourcegroupsiface.ResourceGroupsAPI
+	failures
+
+	// PageSize limits number of resources returned in single page, 0 means no limit
+	PageSize int
+
+	mutex  sync.Mutex
+	ec2    *EC2
+	groups map[string][2]string
+}
+
+// NewResourceGroups creates fake Resource Groups API without any groups
+func NewResourceGroups(ec2Fake *EC2) *ResourceGroups {
+	return &ResourceGroups{
+		ec2:    ec2Fake,
+		groups: make(map[string][2]string),
+	}
+}
+
+// AddGroup adds group which contains resources tagged with given tag
+func (f *ResourceGroups) AddGroup(name, tagKey, tagValue string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.groups[name] = [2]string{tagKey, tagValue}
+}
+
+// GroupExists checks if group was not deleted
+func (f *ResourceGroups) GroupExists(name string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	_, ok := f.groups[name]
+	return ok
+}
+
+// FailOn makes next calls of operation (e.g. "DeleteGroup") on group with given name return errs in order
+func (f *ResourceGroups) FailOn(operation, name string, errs ...error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failures.FailOn(operation, name, errs...)
+}
+
+// Calls returns all recorded calls in form "Operation name" in order in which they were made
+func (f *ResourceGroups) Calls() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.failures.Calls()
+}
+
+// ListGroupResourcesPages returns resources of group in pages
+func (f *ResourceGroups) ListGroupResourcesPages(input *resourcegroups.ListGroupResourcesInput, fn func(*resourcegroups.ListGroupResourcesOutput, bool) bool) error {
+	f.mutex.Lock()
+	name := aws.StringValue(input.GroupName)
+	err := f.call("ListGroupResources", name)
+	query, ok := f.groups[name]
+	f.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewError(resourcegroups.ErrCodeNotFoundException)
+	}
+
+	identifiers := make([]*resourcegroups.ResourceIdentifier, 0)
+	for _, resource := range f.ec2.tagged(query[0], []string{query[1]}) {
+		identifiers = append(identifiers, &resourcegroups.ResourceIdentifier{
+			ResourceArn:  aws.String(resource.arn),
+			ResourceType: aws.String(resource.resourceType),
+		})
+	}
+	pages := pages(f.PageSize, len(identifiers))
+	for i, page := range pages {
+		if !fn(&resourcegroups.ListGroupResourcesOutput{ResourceIdentifiers: identifiers[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// ListGroupsPages returns identifiers of all groups in pages, sorted by name
+func (f *ResourceGroups) ListGroupsPages(input *resourcegroups.ListGroupsInput, fn func(*resourcegroups.ListGroupsOutput, bool) bool) error {
+	f.mutex.Lock()
+	err := f.call("ListGroups", "")
+	names := make([]string, 0, len(f.groups))
+	for name := range f.groups {
+		names = append(names, name)
+	}
+	f.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	identifiers := make([]*resourcegroups.GroupIdentifier, 0, len(names))
+	for _, name := range names {
+		identifiers = append(identifiers, &resourcegroups.GroupIdentifier{
+			GroupName: aws.String(name),
+			GroupArn:  aws.String(fmt.Sprintf("arn:aws:resource-groups:%s:%s:group/%s", Region, Account, name)),
+		})
+	}
+	pages := pages(f.PageSize, len(identifiers))
+	for i, page := range pages {
+		if !fn(&resourcegroups.ListGroupsOutput{GroupIdentifiers: identifiers[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// GetGroupQuery returns tag based query of group, in the same form as module creates it
+func (f *ResourceGroups) GetGroupQuery(input *resourcegroups.GetGroupQueryInput) (*resourcegroups.GetGroupQueryOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	name := aws.StringValue(input.Group)
+	if name == "" {
+		name = aws.StringValue(input.GroupName)
+	}
+	if err := f.call("GetGroupQuery", name); err != nil {
+		return nil, err
+	}
+	query, ok := f.groups[name]
+	if !ok {
+		return nil, NewError(resourcegroups.ErrCodeNotFoundException)
+	}
+	return &resourcegroups.GetGroupQueryOutput{GroupQuery: &resourcegroups.GroupQuery{
+		GroupName: aws.String(name),
+		ResourceQuery: &resourcegroups.ResourceQuery{
+			Type:  aws.String(resourcegroups.QueryTypeTagFilters10),
+			Query: aws.String(fmt.Sprintf(`{"ResourceTypeFilters":["AWS::AllSupported"],"TagFilters":[{"Key":%q,"Values":[%q]}]}`, query[0], query[1])),
+		},
+	}}, nil
+}
+
+// DeleteGroup deletes group, resources of group are not affected
+func (f *ResourceGroups) DeleteGroup(input *resourcegroups.DeleteGroupInput) (*resourcegroups.DeleteGroupOutput, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	name := aws.StringValue(input.GroupName)
+	if err := f.call("DeleteGroup", name); err != nil {
+		return nil, err
+	}
+	if _, ok := f.groups[name]; !ok {
+		return nil, NewError(resourcegroups.ErrCodeNotFoundException)
+	}
+	delete(f.groups, name)
+	return &resourcegroups.DeleteGroupOutput{Group: &resourcegroups.Group{Name: aws.String(name)}}, nil
+}
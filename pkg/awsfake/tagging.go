@@ -0,0 +1,86 @@
+package awsfake
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
+)
+
+// Tagging is in-memory fake of Resource Groups Tagging API, resources are taken from fake EC2.
+// Only methods used by this repository are implemented.
+type Tagging struct {
+	resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
+	failures
+
+	// PageSize limits number of resources returned in single page, 0 means no limit
+	PageSize int
+
+	mutex sync.Mutex
+	ec2   *EC2
+}
+
+// NewTagging creates fake Resource Groups Tagging API
+func NewTagging(ec2Fake *EC2) *Tagging {
+	return &Tagging{ec2: ec2Fake}
+}
+
+// Calls returns all recorded calls in order in which they were made
+func (f *Tagging) Calls() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.failures.Calls()
+}
+
+// GetResourcesPages returns resources matching single tag filter and resource type filters, tag filter without values matches any value
+func (f *Tagging) GetResourcesPages(input *resourcegroupstaggingapi.GetResourcesInput, fn func(*resourcegroupstaggingapi.GetResourcesOutput, bool) bool) error {
+	f.mutex.Lock()
+	err := f.call("GetResources", "")
+	f.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(input.TagFilters) != 1 {
+		panic("awsfake: GetResources supports exactly one tag filter")
+	}
+
+	mappings := make([]*resourcegroupstaggingapi.ResourceTagMapping, 0)
+	key := aws.StringValue(input.TagFilters[0].Key)
+	for _, resource := range f.ec2.tagged(key, aws.StringValueSlice(input.TagFilters[0].Values)) {
+		if len(input.ResourceTypeFilters) > 0 && !matchesResourceType(input.ResourceTypeFilters, resource.arn) {
+			continue
+		}
+		tags := make([]*resourcegroupstaggingapi.Tag, 0, len(resource.tags))
+		for _, tag := range resource.tags {
+			tags = append(tags, &resourcegroupstaggingapi.Tag{Key: tag.Key, Value: tag.Value})
+		}
+		mappings = append(mappings, &resourcegroupstaggingapi.ResourceTagMapping{
+			ResourceARN: aws.String(resource.arn),
+			Tags:        tags,
+		})
+	}
+	pages := pages(f.PageSize, len(mappings))
+	for i, page := range pages {
+		if !fn(&resourcegroupstaggingapi.GetResourcesOutput{ResourceTagMappingList: mappings[page[0]:page[1]]}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+// checks if ARN (arn:aws:ec2:region:account:type/id) matches one of filters in form "ec2:type"
+func matchesResourceType(filters []*string, arn string) bool {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return false
+	}
+	resourceType := parts[2] + ":" + strings.SplitN(parts[5], "/", 2)[0]
+	for _, filter := range aws.StringValueSlice(filters) {
+		if filter == parts[2] || filter == resourceType {
+			return true
+		}
+	}
+	return false
+}
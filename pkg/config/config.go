@@ -0,0 +1,206 @@
+// Package config validates module config file (awsbi-config.yml) before terraform is run.
+// Config is checked against JSON Schema and then against local environment (e.g. existence of
+// rsa_pub_path file), every problem is reported with line and column of the offending value.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+const schemaURL = "awsbi-config.schema.json"
+
+var schema = jsonschema.MustCompileString(schemaURL, Schema)
+
+// Error is single problem found in config file
+type Error struct {
+	File    string
+	Line    int
+	Column  int
+	Path    string // JSON pointer of invalid value, e.g. /awsbi/os
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, e.Path, e.Message)
+}
+
+// Errors are all problems found in config file, ordered by position
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Validate checks config file, returns Errors if file is invalid
+func Validate(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file %s: %w", path, err)
+	}
+	return ValidateBytes(path, data, filepath.Dir(path))
+}
+
+// ValidateBytes checks content of config file read from path, relative rsa_pub_path is resolved
+// against dir
+func ValidateBytes(path string, data []byte, dir string) error {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+	if document.Kind == 0 {
+		return Errors{{File: path, Line: 1, Column: 1, Path: "/", Message: "config file is empty"}}
+	}
+
+	nodes := make(map[string]*yaml.Node)
+	errs, err := validateSchema(path, document.Content[0], nodes)
+	if err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		errs = checkEnvironment(path, nodes, dir)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sortByPosition(errs)
+	return errs
+}
+
+func sortByPosition(errs Errors) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+}
+
+// checks document against schema, nodes are filled with node of every JSON pointer
+func validateSchema(path string, document *yaml.Node, nodes map[string]*yaml.Node) (Errors, error) {
+	if err := schema.Validate(toJSON(document, "", nodes)); err != nil {
+		validationError, ok := err.(*jsonschema.ValidationError)
+		if !ok {
+			return nil, err
+		}
+		var errs Errors
+		for _, leaf := range leaves(validationError) {
+			errs = append(errs, at(path, nodes, leaf.InstanceLocation, leaf.Message))
+		}
+		return errs, nil
+	}
+	return nil, nil
+}
+
+// Load validates config file and reads module parameters from it
+func Load(path string) (*state.Config, error) {
+	if err := Validate(path); err != nil {
+		return nil, err
+	}
+	return state.LoadConfig(path)
+}
+
+// checks things schema cannot express, config is already known to match schema
+func checkEnvironment(path string, nodes map[string]*yaml.Node, dir string) Errors {
+	var errs Errors
+
+	const rsaPubPath = "/awsbi/rsa_pub_path"
+	keyPath := nodes[rsaPubPath].Value
+	if !filepath.IsAbs(keyPath) {
+		keyPath = filepath.Join(dir, keyPath)
+	}
+	if info, err := os.Stat(keyPath); err != nil {
+		errs = append(errs, at(path, nodes, rsaPubPath, fmt.Sprintf("cannot read public key file %s: %v", keyPath, err)))
+	} else if info.IsDir() {
+		errs = append(errs, at(path, nodes, rsaPubPath, fmt.Sprintf("public key %s is a directory", keyPath)))
+	}
+
+	return errs
+}
+
+// builds error for value at JSON pointer, position of the closest existing parent is used
+// when value is missing
+func at(path string, nodes map[string]*yaml.Node, pointer string, message string) Error {
+	location := pointer
+	node, ok := nodes[location]
+	for !ok && location != "" {
+		location = location[:strings.LastIndex(location, "/")]
+		node, ok = nodes[location]
+	}
+	if pointer == "" {
+		pointer = "/"
+	}
+	return Error{File: path, Line: node.Line, Column: node.Column, Path: pointer, Message: message}
+}
+
+// returns errors which have no causes, those describe actual problems
+func leaves(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var result []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		result = append(result, leaves(cause)...)
+	}
+	return result
+}
+
+// converts YAML node to value understood by JSON Schema validator and remembers node of every
+// JSON pointer
+func toJSON(node *yaml.Node, pointer string, nodes map[string]*yaml.Node) interface{} {
+	nodes[pointer] = node
+	switch node.Kind {
+	case yaml.AliasNode:
+		return toJSON(node.Alias, pointer, nodes)
+	case yaml.MappingNode:
+		result := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			child := pointer + "/" + escape(key)
+			result[key] = toJSON(node.Content[i+1], child, nodes)
+			if node.Content[i+1].Kind != yaml.ScalarNode {
+				// errors of collections point to their key rather than to their first element
+				nodes[child] = node.Content[i]
+			}
+		}
+		return result
+	case yaml.SequenceNode:
+		result := make([]interface{}, 0, len(node.Content))
+		for i, item := range node.Content {
+			result = append(result, toJSON(item, pointer+"/"+strconv.Itoa(i), nodes))
+		}
+		return result
+	default:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return node.Value
+		}
+		switch value.(type) {
+		case nil, bool, int, int64, uint64, float64, string:
+			return value
+		default:
+			// e.g. timestamps
+			return node.Value
+		}
+	}
+}
+
+// escapes key as JSON pointer token
+func escape(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validConfig = `kind: awsbi-config
+schema_version: 2
+awsbi:
+  name: epiphany
+  instance_count: 1
+  region: eu-central-1
+  use_public_ip: false
+  nat_gateway_count: 1
+  subnets:
+    private:
+      count: 1
+    public:
+      count: 1
+  rsa_pub_path: "vms_rsa.pub"
+  os: redhat
+`
+
+// creates directory with public key used by configs
+func keyDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "vms_rsa.pub"), []byte("ssh-rsa AAAA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestValidConfig(t *testing.T) {
+	// given
+	dir := keyDir(t)
+	defer os.RemoveAll(dir)
+
+	// when
+	err := ValidateBytes("awsbi-config.yml", []byte(validConfig), dir)
+
+	// then
+	if err != nil {
+		t.Error("Expected valid config, got ", err)
+	}
+}
+
+func TestInvalidConfigReportsPositions(t *testing.T) {
+	// given
+	dir := keyDir(t)
+	defer os.RemoveAll(dir)
+	config := strings.Replace(validConfig, "os: redhat", "os: windows", 1)
+	config = strings.Replace(config, "instance_count: 1", "instance_count: many", 1)
+	config = strings.Replace(config, "    public:\n      count: 1\n", "    public: {}\n", 1)
+
+	// when
+	err := ValidateBytes("awsbi-config.yml", []byte(config), dir)
+
+	// then
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatal("Expected validation errors, got ", err)
+	}
+	expected := []struct {
+		line int
+		path string
+	}{
+		{5, "/awsbi/instance_count"},
+		{12, "/awsbi/subnets/public"},
+		{14, "/awsbi/os"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatal("Expected ", len(expected), " errors, got ", errs)
+	}
+	for i, e := range expected {
+		if errs[i].Line != e.line || errs[i].Path != e.path {
+			t.Error("Expected error at line ", e.line, " of ", e.path, " got ", errs[i])
+		}
+	}
+	if !strings.HasPrefix(errs[2].Error(), "awsbi-config.yml:14:7: /awsbi/os: ") {
+		t.Error("Expected message with position, got ", errs[2].Error())
+	}
+}
+
+func TestMissingPublicKey(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// when
+	err = ValidateBytes("awsbi-config.yml", []byte(validConfig), dir)
+
+	// then
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatal("Expected single validation error, got ", err)
+	}
+	if errs[0].Line != 14 || errs[0].Path != "/awsbi/rsa_pub_path" {
+		t.Error("Expected error of rsa_pub_path at line 14, got ", errs[0])
+	}
+}
+
+func TestMissingSection(t *testing.T) {
+	// when
+	err := ValidateBytes("awsbi-config.yml", []byte("kind: awsbi-config\n"), os.TempDir())
+
+	// then
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatal("Expected single validation error, got ", err)
+	}
+	if errs[0].Line != 1 || !strings.Contains(errs[0].Message, "awsbi") {
+		t.Error("Expected missing awsbi at line 1, got ", errs[0])
+	}
+}
+
+func TestPublishedSchemaIsUpToDate(t *testing.T) {
+	// given
+	published, err := ioutil.ReadFile("../../resources/schema/awsbi-config.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	if string(published) != Schema {
+		t.Error("Expected resources/schema/awsbi-config.schema.json to be the same as Schema")
+	}
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/migration"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+// Input is environment variable setting one module parameter, Type is the one documented in
+// docs/INPUTS.adoc
+type Input struct {
+	Name string
+	Type string
+	Key  string // key in awsbi section of config file
+}
+
+// Input types
+const (
+	Number = "number"
+	Bool   = "bool"
+	Map    = "map"
+	String = "string"
+)
+
+// Inputs are all environment variables setting module parameters, in order of config file keys
+var Inputs = []Input{
+	{"M_NAME", String, "name"},
+	{"M_VMS_COUNT", Number, "instance_count"},
+	{"M_REGION", String, "region"},
+	{"M_PUBLIC_IPS", Bool, "use_public_ip"},
+	{"M_NAT_GATEWAY_COUNT", Number, "nat_gateway_count"},
+	{"M_SUBNETS", Map, "subnets"},
+	{"M_VMS_RSA", String, "rsa_pub_path"},
+	{"M_OS", String, "os"},
+}
+
+// sharedDir is the variable with path of shared directory, rsa_pub_path is M_VMS_RSA key in it
+const sharedDir = "M_SHARED"
+
+// properties are schemas of parameters by key, every input is checked against its own schema
+var properties = compileProperties()
+
+// InputError is invalid value of single input
+type InputError struct {
+	Name    string
+	Value   string
+	Message string
+}
+
+func (e InputError) Error() string {
+	return fmt.Sprintf("%s=%q: %s", e.Name, e.Value, e.Message)
+}
+
+// InputErrors are all invalid inputs, in order of Inputs
+type InputErrors []InputError
+
+func (e InputErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// FromEnvironment builds module parameters from inputs read with getenv (e.g. os.Getenv), returns
+// InputErrors with every invalid input
+func FromEnvironment(getenv func(string) string) (*state.Config, error) {
+	return Build("", nil, getenv)
+}
+
+// Build builds module parameters from content of config file (YAML or JSON) read from path merged
+// with inputs read with getenv, data may be nil when there is no config file. Parameters missing
+// in config file are taken from inputs, so defaults of inputs apply to them. Config file with
+// older schema version is migrated first. Returns InputErrors when inputs are invalid and Errors
+// when config file is invalid.
+func Build(path string, data []byte, getenv func(string) string) (*state.Config, error) {
+	document, err := parseConfig(path, data)
+	if err != nil {
+		return nil, err
+	}
+	section := child(document, state.ModuleKey)
+
+	var inputErrs InputErrors
+	for _, input := range Inputs {
+		if child(section, input.Key) != nil {
+			continue
+		}
+		node, err := input.node(getenv)
+		if err != nil {
+			inputErrs = append(inputErrs, *err)
+			continue
+		}
+		section.Content = append(section.Content, scalar(input.Key, "!!str"), node)
+	}
+	if len(inputErrs) > 0 {
+		return nil, inputErrs
+	}
+
+	errs, err := validateSchema(path, document, make(map[string]*yaml.Node))
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		sortByPosition(errs)
+		return nil, errs
+	}
+
+	var config state.Config
+	if err := section.Decode(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Render returns content of config file with module parameters built by Build
+func Render(path string, data []byte, getenv func(string) string) ([]byte, error) {
+	config, err := Build(path, data, getenv)
+	if err != nil {
+		return nil, err
+	}
+	return state.MarshalConfig(*config)
+}
+
+// returns document of config file with kind, current schema version and module section, missing
+// ones are added
+func parseConfig(path string, data []byte) (*yaml.Node, error) {
+	var file yaml.Node
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+	document := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(file.Content) > 0 {
+		document = file.Content[0]
+	}
+	if document.Kind != yaml.MappingNode {
+		return nil, Errors{{File: path, Line: document.Line, Column: document.Column, Path: "/", Message: "config file has to be a mapping"}}
+	}
+
+	if child(document, "schema_version") != nil {
+		if _, err := migration.Config.Migrate(document); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else {
+		// config file written by hand is expected to have the current layout
+		document.Content = append([]*yaml.Node{scalar("schema_version", "!!str"), scalar(strconv.Itoa(state.ConfigSchemaVersion), "!!int")}, document.Content...)
+	}
+	if child(document, "kind") == nil {
+		document.Content = append([]*yaml.Node{scalar("kind", "!!str"), scalar(state.ConfigKind, "!!str")}, document.Content...)
+	}
+
+	section := child(document, state.ModuleKey)
+	if section == nil {
+		section = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		document.Content = append(document.Content, scalar(state.ModuleKey, "!!str"), section)
+	}
+	if section.Kind != yaml.MappingNode {
+		return nil, Errors{{File: path, Line: section.Line, Column: section.Column, Path: "/" + state.ModuleKey, Message: "has to be a mapping"}}
+	}
+	return document, nil
+}
+
+// returns node of input value read with getenv
+func (i Input) node(getenv func(string) string) (*yaml.Node, *InputError) {
+	value := getenv(i.Name)
+	node, err := i.parse(value)
+	if err != nil {
+		return nil, &InputError{Name: i.Name, Value: value, Message: err.Error()}
+	}
+	if i.Key == "rsa_pub_path" {
+		shared := getenv(sharedDir)
+		if shared == "" {
+			return nil, &InputError{Name: sharedDir, Message: "is not set"}
+		}
+		node.Value = filepath.Join(shared, node.Value+".pub")
+	}
+	return node, nil
+}
+
+// returns value node of key in mapping node, nil if there is no such key
+func child(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalar(value, tag string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// parses value of input into YAML node of its type and checks it against schema of its key
+func (i Input) parse(value string) (*yaml.Node, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("is not set, expected %s", i.Type)
+	}
+
+	var node *yaml.Node
+	switch i.Type {
+	case Number:
+		if _, err := strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("expected number, e.g. 1")
+		}
+		node = scalar(value, "!!int")
+	case Bool:
+		if value != "true" && value != "false" {
+			return nil, fmt.Errorf("expected bool, true or false")
+		}
+		node = scalar(value, "!!bool")
+	case Map:
+		var document yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &document); err != nil {
+			return nil, fmt.Errorf("expected map: %v", err)
+		}
+		if len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("expected map, e.g. {private: {count: 1}, public: {count: 1}}")
+		}
+		node = document.Content[0]
+	default:
+		node = scalar(value, "!!str")
+	}
+
+	if err := properties[i.Key].Validate(toJSON(node, "", make(map[string]*yaml.Node))); err != nil {
+		validationError, ok := err.(*jsonschema.ValidationError)
+		if !ok {
+			return nil, err
+		}
+		var messages []string
+		for _, leaf := range leaves(validationError) {
+			message := leaf.Message
+			if leaf.InstanceLocation != "" {
+				message = strings.TrimPrefix(leaf.InstanceLocation, "/") + ": " + message
+			}
+			messages = append(messages, message)
+		}
+		return nil, errors.New(strings.Join(messages, ", "))
+	}
+	return node, nil
+}
+
+func compileProperties() map[string]*jsonschema.Schema {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, strings.NewReader(Schema)); err != nil {
+		panic(err)
+	}
+	result := make(map[string]*jsonschema.Schema, len(Inputs))
+	for _, input := range Inputs {
+		result[input.Key] = compiler.MustCompile(schemaURL + "#/properties/" + state.ModuleKey + "/properties/" + input.Key)
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// inputs are default values from resources/defaults.mk
+var inputs = map[string]string{
+	"M_NAME":              "epiphany",
+	"M_VMS_COUNT":         "1",
+	"M_REGION":            "eu-central-1",
+	"M_PUBLIC_IPS":        "false",
+	"M_NAT_GATEWAY_COUNT": "1",
+	"M_SUBNETS":           "{\n  private: {\n    count: 1\n  },\n  public: {\n    count: 1\n  }\n}",
+	"M_VMS_RSA":           "vms_rsa",
+	"M_OS":                "redhat",
+	"M_SHARED":            "/shared",
+}
+
+// returns getenv function reading inputs with some values replaced
+func environment(replaced map[string]string) func(string) string {
+	return func(name string) string {
+		if value, ok := replaced[name]; ok {
+			return value
+		}
+		return inputs[name]
+	}
+}
+
+func TestRenderDefaults(t *testing.T) {
+	// when
+	data, err := Render("", nil, environment(nil))
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `kind: awsbi-config
+schema_version: 2
+awsbi:
+  name: epiphany
+  instance_count: 1
+  region: eu-central-1
+  use_public_ip: false
+  nat_gateway_count: 1
+  subnets:
+    private:
+      count: 1
+    public:
+      count: 1
+  rsa_pub_path: /shared/vms_rsa.pub
+  os: redhat
+`
+	if string(data) != expected {
+		t.Error("Expected:\n", expected, "\ngot:\n", string(data))
+	}
+	if err := ValidateBytes("awsbi-config.yml", data, "/"); err != nil && !strings.Contains(err.Error(), "rsa_pub_path") {
+		t.Error("Expected rendered config to match schema, got ", err)
+	}
+}
+
+func TestInvalidInputsAreReportedTogether(t *testing.T) {
+	// when
+	_, err := FromEnvironment(environment(map[string]string{
+		"M_VMS_COUNT":  "two",
+		"M_PUBLIC_IPS": "yes",
+		"M_SUBNETS":    "{private: {count: 1}, public: {count: -1}}",
+		"M_OS":         "windows",
+		"M_REGION":     "",
+	}))
+
+	// then
+	errs, ok := err.(InputErrors)
+	if !ok {
+		t.Fatal("Expected input errors, got ", err)
+	}
+	expected := []string{"M_VMS_COUNT", "M_REGION", "M_PUBLIC_IPS", "M_SUBNETS", "M_OS"}
+	if len(errs) != len(expected) {
+		t.Fatal("Expected ", len(expected), " errors, got ", errs)
+	}
+	for i, name := range expected {
+		if errs[i].Name != name {
+			t.Error("Expected error of ", name, " got ", errs[i])
+		}
+	}
+	if !strings.Contains(errs[3].Message, "public/count") {
+		t.Error("Expected error to point to public/count, got ", errs[3])
+	}
+}
+
+func TestMalformedSubnets(t *testing.T) {
+	for _, subnets := range []string{"{private: {count: 1}", "3", "{private: {count: 1}}", "{private: {count: 1}, public: {count: 1}, other: {}}"} {
+		// when
+		_, err := FromEnvironment(environment(map[string]string{"M_SUBNETS": subnets}))
+
+		// then
+		errs, ok := err.(InputErrors)
+		if !ok || len(errs) != 1 || errs[0].Name != "M_SUBNETS" {
+			t.Error("Expected error of M_SUBNETS=", subnets, " got ", err)
+		}
+	}
+}
+
+func TestBuildMergesConfigFileWithInputs(t *testing.T) {
+	for _, file := range []string{
+		"awsbi:\n  instance_count: 3\n  subnets:\n    private:\n      count: 2\n    public:\n      count: 2\n",
+		`{"kind": "awsbi-config", "awsbi": {"instance_count": 3, "subnets": {"private": {"count": 2}, "public": {"count": 2}}}}`,
+	} {
+		// when
+		config, err := Build("env.yml", []byte(file), environment(map[string]string{"M_SUBNETS": "invalid"}))
+
+		// then
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.InstanceCount != 3 || config.Subnets.Private.Count != 2 || config.Subnets.Public.Count != 2 {
+			t.Error("Expected values from config file, got ", config)
+		}
+		if config.Name != "epiphany" || config.OS != "redhat" || config.RsaPubPath != "/shared/vms_rsa.pub" {
+			t.Error("Expected values from inputs, got ", config)
+		}
+	}
+}
+
+func TestBuildMigratesConfigFile(t *testing.T) {
+	// when
+	config, err := Build("env.yml", []byte("kind: awsbi-config\nschema_version: 1\nawsbi:\n  instance_count: 2\n"), environment(nil))
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.InstanceCount != 2 || config.OS != "ubuntu" {
+		t.Error("Expected config migrated from schema version 1, got ", config)
+	}
+}
+
+func TestBuildReportsPositionsInConfigFile(t *testing.T) {
+	// when
+	_, err := Build("env.yml", []byte("awsbi:\n  instance_count: many\n  size: 3\n"), environment(nil))
+
+	// then
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatal("Expected 2 config errors, got ", err)
+	}
+	if errs[0].Line != 1 || errs[1].Line != 2 || errs[1].Path != "/awsbi/instance_count" {
+		t.Error("Expected errors at lines 1 and 2 of env.yml, got ", errs)
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+// Schema is JSON Schema of module config file, published as resources/schema/awsbi-config.schema.json
+const Schema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "awsbi module config",
+  "type": "object",
+  "required": ["kind", "schema_version", "awsbi"],
+  "additionalProperties": false,
+  "properties": {
+    "kind": {
+      "const": "awsbi-config"
+    },
+    "schema_version": {
+      "description": "Version of config file layout, older files are migrated by init and plan",
+      "const": 2
+    },
+    "awsbi": {
+      "type": "object",
+      "required": [
+        "name",
+        "instance_count",
+        "region",
+        "use_public_ip",
+        "nat_gateway_count",
+        "subnets",
+        "rsa_pub_path",
+        "os"
+      ],
+      "additionalProperties": false,
+      "properties": {
+        "name": {
+          "description": "Prefix of names of all resources",
+          "type": "string",
+          "pattern": "^[a-zA-Z][a-zA-Z0-9-]*$",
+          "maxLength": 60
+        },
+        "instance_count": {
+          "description": "Number of virtual machines",
+          "type": "integer",
+          "minimum": 0
+        },
+        "region": {
+          "description": "AWS region, e.g. eu-central-1",
+          "type": "string",
+          "pattern": "^[a-z]{2}(-gov)?-[a-z]+-[0-9]+$"
+        },
+        "use_public_ip": {
+          "description": "If true virtual machines have public IP addresses",
+          "type": "boolean"
+        },
+        "nat_gateway_count": {
+          "description": "Number of NAT gateways",
+          "type": "integer",
+          "minimum": 0
+        },
+        "subnets": {
+          "type": "object",
+          "required": ["private", "public"],
+          "additionalProperties": false,
+          "properties": {
+            "private": {
+              "$ref": "#/definitions/subnetGroup"
+            },
+            "public": {
+              "$ref": "#/definitions/subnetGroup"
+            }
+          }
+        },
+        "rsa_pub_path": {
+          "description": "Path of public SSH key installed on virtual machines",
+          "type": "string",
+          "minLength": 1
+        },
+        "os": {
+          "description": "Operating system of virtual machines",
+          "enum": ["redhat", "ubuntu"]
+        },
+        "root_volume_size": {
+          "description": "Size of root volume of virtual machines in GiB, 64 when not set",
+          "type": "integer",
+          "minimum": 1
+        }
+      }
+    }
+  },
+  "definitions": {
+    "subnetGroup": {
+      "type": "object",
+      "required": ["count"],
+      "additionalProperties": false,
+      "properties": {
+        "count": {
+          "type": "integer",
+          "minimum": 0
+        }
+      }
+    }
+  }
+}
+`
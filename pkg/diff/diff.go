@@ -0,0 +1,207 @@
+// Package diff compares two documents decoded from YAML or JSON key by key and reports changes in
+// a human readable or JSON form. Values of different types are never equal (e.g. "1" and 1),
+// numbers are compared by value regardless of their Go type.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Action tells what happens with value
+type Action string
+
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+// Change is single difference between documents. Before is nil for created values, After is nil
+// for deleted ones.
+type Change struct {
+	Path   string      `json:"path"`
+	Action Action      `json:"action"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Compare returns changes which turn before into after, ordered by path. Root is the path prefix
+// of compared documents, e.g. name of compared section.
+func Compare(root string, before, after interface{}) []Change {
+	var changes []Change
+	compare(root, before, after, &changes)
+	return changes
+}
+
+func compare(path string, before, after interface{}, changes *[]Change) {
+	beforeType, afterType := typeOf(before), typeOf(after)
+	if beforeType != afterType {
+		*changes = append(*changes, Change{Path: path, Action: Update, Before: before, After: after})
+		return
+	}
+
+	switch beforeType {
+	case "object":
+		b, a := before.(map[string]interface{}), after.(map[string]interface{})
+		for _, key := range keys(b, a) {
+			bv, inBefore := b[key]
+			av, inAfter := a[key]
+			switch {
+			case !inAfter:
+				*changes = append(*changes, Change{Path: join(path, key), Action: Delete, Before: bv})
+			case !inBefore:
+				*changes = append(*changes, Change{Path: join(path, key), Action: Create, After: av})
+			default:
+				compare(join(path, key), bv, av, changes)
+			}
+		}
+	case "array":
+		b, a := before.([]interface{}), after.([]interface{})
+		for i := 0; i < len(b) || i < len(a); i++ {
+			itemPath := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(a):
+				*changes = append(*changes, Change{Path: itemPath, Action: Delete, Before: b[i]})
+			case i >= len(b):
+				*changes = append(*changes, Change{Path: itemPath, Action: Create, After: a[i]})
+			default:
+				compare(itemPath, b[i], a[i], changes)
+			}
+		}
+	case "number":
+		if toFloat(before) != toFloat(after) {
+			*changes = append(*changes, Change{Path: path, Action: Update, Before: before, After: after})
+		}
+	default:
+		if before != after {
+			*changes = append(*changes, Change{Path: path, Action: Update, Before: before, After: after})
+		}
+	}
+}
+
+// Text writes changes in human readable form, one change per line
+func Text(w io.Writer, changes []Change) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+	for _, change := range changes {
+		var line string
+		switch change.Action {
+		case Create:
+			line = fmt.Sprintf("+ %s: %s", change.Path, format(change.After))
+		case Delete:
+			line = fmt.Sprintf("- %s: %s", change.Path, format(change.Before))
+		default:
+			before, after := format(change.Before), format(change.After)
+			if beforeType, afterType := typeOf(change.Before), typeOf(change.After); beforeType != afterType {
+				before += " (" + beforeType + ")"
+				after += " (" + afterType + ")"
+			}
+			line = fmt.Sprintf("~ %s: %s -> %s", change.Path, before, after)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d to create, %d to update, %d to delete.\n", count(changes, Create), count(changes, Update), count(changes, Delete))
+	return err
+}
+
+// JSON writes changes as JSON document
+func JSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Changes []Change `json:"changes"`
+	}{changes})
+}
+
+// returns JSON type name of value
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64, float64, json.Number:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	}
+	return 0
+}
+
+// formats value in single line
+func format(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// returns sorted keys of both maps
+func keys(maps ...map[string]interface{}) []string {
+	set := make(map[string]bool)
+	for _, m := range maps {
+		for key := range m {
+			set[key] = true
+		}
+	}
+	result := make([]string, 0, len(set))
+	for key := range set {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func join(path, key string) string {
+	if strings.ContainsAny(key, ".[] ") {
+		key = strconv.Quote(key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func count(changes []Change, action Action) int {
+	n := 0
+	for _, change := range changes {
+		if change.Action == action {
+			n++
+		}
+	}
+	return n
+}
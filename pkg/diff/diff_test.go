@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func decode(t *testing.T, document string) interface{} {
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(document), &value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestCompare(t *testing.T) {
+	// given
+	before := decode(t, `
+name: epiphany
+instance_count: 1
+use_public_ip: false
+removed: x
+subnets:
+  private:
+    count: 1
+zones: [a, b]
+`)
+	after := decode(t, `
+name: epiphany
+instance_count: "1"
+use_public_ip: true
+os: ubuntu
+subnets:
+  private:
+    count: 1.0
+zones: [a]
+`)
+
+	// when
+	changes := Compare("awsbi", before, after)
+
+	// then
+	expected := []Change{
+		{Path: "awsbi.instance_count", Action: Update, Before: 1, After: "1"},
+		{Path: "awsbi.os", Action: Create, After: "ubuntu"},
+		{Path: "awsbi.removed", Action: Delete, Before: "x"},
+		{Path: "awsbi.use_public_ip", Action: Update, Before: false, After: true},
+		{Path: "awsbi.zones[1]", Action: Delete, Before: "b"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Error("Expected ", expected, " got ", changes)
+	}
+}
+
+func TestCompareEqual(t *testing.T) {
+	// given
+	document := decode(t, "name: epiphany\nsubnets: {private: {count: 1}}\n")
+
+	// when
+	changes := Compare("awsbi", document, decode(t, "subnets: {private: {count: 1}}\nname: epiphany\n"))
+
+	// then
+	if len(changes) != 0 {
+		t.Error("Expected no changes, got ", changes)
+	}
+}
+
+func TestText(t *testing.T) {
+	// given
+	changes := []Change{
+		{Path: "awsbi.instance_count", Action: Update, Before: 1, After: "2"},
+		{Path: "awsbi.os", Action: Create, After: "ubuntu"},
+		{Path: "awsbi.use_public_ip", Action: Update, Before: false, After: true},
+	}
+	var out bytes.Buffer
+
+	// when
+	err := Text(&out, changes)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `~ awsbi.instance_count: 1 (number) -> "2" (string)
++ awsbi.os: "ubuntu"
+~ awsbi.use_public_ip: false -> true
+1 to create, 2 to update, 0 to delete.
+`
+	if out.String() != expected {
+		t.Error("Expected:\n", expected, "\ngot:\n", out.String())
+	}
+}
+
+func TestJSONWithoutChanges(t *testing.T) {
+	// given
+	var out bytes.Buffer
+
+	// when
+	err := JSON(&out, nil)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{\n  \"changes\": []\n}\n"; out.String() != expected {
+		t.Error("Expected ", expected, " got ", out.String())
+	}
+}
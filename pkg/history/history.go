@@ -0,0 +1,268 @@
+// Package history keeps snapshots of module files (state file, config file and terraform state)
+// taken after each of their changes. Every snapshot is a directory named by its serial which grows
+// with each snapshot, so previous set of files can be found and restored.
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/lock"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+const (
+	// DefaultKeep is the number of most recent snapshots kept
+	DefaultKeep = 100
+	// metadataFile is the name of file describing snapshot in its directory
+	metadataFile = "snapshot.json"
+)
+
+// now returns current time, replaced in tests
+var now = time.Now
+
+// Snapshot describes single snapshot, Files are base names of files it contains
+type Snapshot struct {
+	Serial  int       `json:"serial"`
+	Created time.Time `json:"created"`
+	Command string    `json:"command"`
+	Version string    `json:"version"`
+	Files   []string  `json:"files"`
+}
+
+// History is a directory of snapshots of files
+type History struct {
+	dir       string
+	stateFile string
+	files     []string
+	// Keep is the number of snapshots kept, older ones are removed
+	Keep int
+	// LockWait is how long to wait for history locked by another module run
+	LockWait time.Duration
+}
+
+// New returns history kept in dir of state file and other files, files need to have different
+// base names
+func New(dir string, stateFile string, others ...string) (*History, error) {
+	files := append([]string{stateFile}, others...)
+	names := make(map[string]bool)
+	for _, file := range files {
+		name := filepath.Base(file)
+		if names[name] {
+			return nil, fmt.Errorf("history cannot keep two files named %s", name)
+		}
+		names[name] = true
+	}
+	return &History{dir: dir, stateFile: stateFile, files: files, Keep: DefaultKeep}, nil
+}
+
+// Snapshot copies current versions of files into new snapshot, nothing is done and nil is returned
+// when files did not change since the latest snapshot
+func (h *History) Snapshot(command, version string) (*Snapshot, error) {
+	if err := os.MkdirAll(h.dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create history directory: %w", err)
+	}
+	l, err := lock.Acquire(h.dir, lock.Options{Owner: command, Wait: h.LockWait})
+	if err != nil {
+		return nil, err
+	}
+	defer l.Release()
+
+	contents := make(map[string][]byte)
+	for _, file := range h.files {
+		data, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", file, err)
+		}
+		contents[filepath.Base(file)] = data
+	}
+
+	snapshots, err := h.List()
+	if err != nil {
+		return nil, err
+	}
+	serial := 1
+	if len(snapshots) > 0 {
+		latest := snapshots[len(snapshots)-1]
+		unchanged, err := h.equal(latest, contents)
+		if err != nil || unchanged {
+			return nil, err
+		}
+		serial = latest.Serial + 1
+	}
+
+	snapshot := Snapshot{Serial: serial, Created: now().UTC(), Command: command, Version: version}
+	tmp, err := ioutil.TempDir(h.dir, ".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create snapshot: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	for name, data := range contents {
+		if err := ioutil.WriteFile(filepath.Join(tmp, name), data, 0644); err != nil {
+			return nil, fmt.Errorf("cannot create snapshot: %w", err)
+		}
+		snapshot.Files = append(snapshot.Files, name)
+	}
+	sort.Strings(snapshot.Files)
+	metadata, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, metadataFile), metadata, 0644); err != nil {
+		return nil, fmt.Errorf("cannot create snapshot: %w", err)
+	}
+	// snapshot appears at once, so it is never seen partially written
+	if err := os.Rename(tmp, h.path(serial)); err != nil {
+		return nil, fmt.Errorf("cannot create snapshot: %w", err)
+	}
+
+	if err := h.prune(append(snapshots, snapshot)); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// List returns all snapshots ordered by serial
+func (h *History) List() ([]Snapshot, error) {
+	entries, err := ioutil.ReadDir(h.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read history directory: %w", err)
+	}
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil || !entry.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(h.dir, entry.Name(), metadataFile))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read snapshot %s: %w", entry.Name(), err)
+		}
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("cannot read snapshot %s: %w", entry.Name(), err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Serial < snapshots[j].Serial })
+	return snapshots, nil
+}
+
+// Get returns snapshot with serial and content of its files by base name
+func (h *History) Get(serial int) (*Snapshot, map[string][]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(h.path(serial), metadataFile))
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("there is no snapshot %d", serial)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read snapshot %d: %w", serial, err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, nil, fmt.Errorf("cannot read snapshot %d: %w", serial, err)
+	}
+	contents, err := h.read(snapshot)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &snapshot, contents, nil
+}
+
+// Restore writes files of snapshot back to their paths. State file is handled separately: only
+// module section is restored, sections of other modules stay as they are now. Files which are not
+// in snapshot are left untouched.
+func (h *History) Restore(serial int) (*Snapshot, error) {
+	snapshot, contents, err := h.Get(serial)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range h.files {
+		data, ok := contents[filepath.Base(file)]
+		if !ok {
+			continue
+		}
+		if file == h.stateFile {
+			data, err = restoreSection(file, data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := state.WriteFileAtomic(file, data); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// returns current state file with module section taken from old one
+func restoreSection(path string, old []byte) ([]byte, error) {
+	current, err := state.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := state.Parse(old)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse state file from snapshot: %w", err)
+	}
+	current.RestoreAWSBI(previous)
+	return current.Bytes()
+}
+
+// reads files of snapshot
+func (h *History) read(snapshot Snapshot) (map[string][]byte, error) {
+	contents := make(map[string][]byte, len(snapshot.Files))
+	for _, name := range snapshot.Files {
+		data, err := ioutil.ReadFile(filepath.Join(h.path(snapshot.Serial), name))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read snapshot %d: %w", snapshot.Serial, err)
+		}
+		contents[name] = data
+	}
+	return contents, nil
+}
+
+// tells if snapshot has the same files with the same content
+func (h *History) equal(snapshot Snapshot, contents map[string][]byte) (bool, error) {
+	if len(snapshot.Files) != len(contents) {
+		return false, nil
+	}
+	previous, err := h.read(snapshot)
+	if err != nil {
+		return false, err
+	}
+	for name, data := range contents {
+		if !bytes.Equal(previous[name], data) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// removes the oldest snapshots above limit
+func (h *History) prune(snapshots []Snapshot) error {
+	if h.Keep <= 0 || len(snapshots) <= h.Keep {
+		return nil
+	}
+	for _, snapshot := range snapshots[:len(snapshots)-h.Keep] {
+		if err := os.RemoveAll(h.path(snapshot.Serial)); err != nil {
+			return fmt.Errorf("cannot remove snapshot %d: %w", snapshot.Serial, err)
+		}
+	}
+	return nil
+}
+
+func (h *History) path(serial int) string {
+	return filepath.Join(h.dir, fmt.Sprintf("%06d", serial))
+}
@@ -0,0 +1,181 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type files struct {
+	dir     string
+	state   string
+	config  string
+	tfstate string
+}
+
+func newFiles(t *testing.T) files {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files{
+		dir:     dir,
+		state:   filepath.Join(dir, "state.yml"),
+		config:  filepath.Join(dir, "awsbi", "awsbi-config.yml"),
+		tfstate: filepath.Join(dir, "awsbi", "terraform.tfstate"),
+	}
+}
+
+func (f files) history(t *testing.T) *History {
+	h, err := New(filepath.Join(f.dir, "awsbi", "history"), f.state, f.config, f.tfstate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func write(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSnapshotSerials(t *testing.T) {
+	// given
+	f := newFiles(t)
+	defer os.RemoveAll(f.dir)
+	h := f.history(t)
+	write(t, f.config, "kind: awsbi-config\n")
+
+	// when
+	first, err := h.Snapshot("awsbi snapshot", "0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unchanged, err := h.Snapshot("awsbi snapshot", "0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, f.state, "kind: state\nawsbi:\n  status: initialized\n")
+	second, err := h.Snapshot("awsbi init-state", "0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	if first == nil || first.Serial != 1 || len(first.Files) != 1 {
+		t.Error("Expected first snapshot with config only, got ", first)
+	}
+	if unchanged != nil {
+		t.Error("Expected no snapshot when files did not change, got ", unchanged)
+	}
+	if second == nil || second.Serial != 2 || len(second.Files) != 2 {
+		t.Error("Expected second snapshot with state and config, got ", second)
+	}
+	snapshots, err := h.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[1].Command != "awsbi init-state" {
+		t.Error("Expected 2 snapshots, got ", snapshots)
+	}
+}
+
+func TestSnapshotPrunesOldest(t *testing.T) {
+	// given
+	f := newFiles(t)
+	defer os.RemoveAll(f.dir)
+	h := f.history(t)
+	h.Keep = 3
+
+	// when
+	for i := 0; i < 5; i++ {
+		write(t, f.tfstate, strings.Repeat("x", i+1))
+		if _, err := h.Snapshot("awsbi with-lock", "0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// then
+	snapshots, err := h.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 3 || snapshots[0].Serial != 3 || snapshots[2].Serial != 5 {
+		t.Error("Expected snapshots 3 to 5, got ", snapshots)
+	}
+}
+
+func TestRestoreKeepsOtherModules(t *testing.T) {
+	// given
+	f := newFiles(t)
+	defer os.RemoveAll(f.dir)
+	h := f.history(t)
+	write(t, f.state, "kind: state\nawsbi:\n  status: applied\n  name: old\n")
+	write(t, f.config, "kind: awsbi-config\nawsbi:\n  name: old\n")
+	write(t, f.tfstate, "old")
+	if _, err := h.Snapshot("awsbi update-after-apply", "0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	write(t, f.state, "kind: state\nazbi:\n  status: applied\nawsbi:\n  status: initialized\n  name: new\n")
+	write(t, f.config, "kind: awsbi-config\nawsbi:\n  name: new\n")
+	write(t, f.tfstate, "new")
+
+	// when
+	snapshot, err := h.Restore(1)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Serial != 1 {
+		t.Error("Expected snapshot 1, got ", snapshot)
+	}
+	expectedState := "kind: state\nazbi:\n  status: applied\nawsbi:\n  status: applied\n  name: old\n"
+	if content := read(t, f.state); content != expectedState {
+		t.Error("Expected state:\n", expectedState, "\ngot:\n", content)
+	}
+	if content := read(t, f.config); !strings.Contains(content, "name: old") {
+		t.Error("Expected old config, got ", content)
+	}
+	if content := read(t, f.tfstate); content != "old" {
+		t.Error("Expected old terraform state, got ", content)
+	}
+}
+
+func TestRestoreMissingSnapshot(t *testing.T) {
+	// given
+	f := newFiles(t)
+	defer os.RemoveAll(f.dir)
+
+	// when
+	_, err := f.history(t).Restore(7)
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "no snapshot 7") {
+		t.Error("Expected missing snapshot error, got ", err)
+	}
+}
+
+func TestNewRejectsDuplicateNames(t *testing.T) {
+	// when
+	_, err := New("history", "a/state.yml", "b/state.yml")
+
+	// then
+	if err == nil {
+		t.Error("Expected error of files with the same name")
+	}
+}
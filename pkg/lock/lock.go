@@ -0,0 +1,243 @@
+// Package lock implements advisory locks of files on volume shared by module containers. Lock of
+// a file is another file next to it (path + ".lock") created exclusively and holding information
+// about its owner. Lock expires when it is not refreshed, so lock of crashed process does not block
+// others forever.
+package lock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+const (
+	// Suffix is added to path of locked file to get path of lock file
+	Suffix = ".lock"
+	// DefaultTTL is time after which lock expires when it is not refreshed
+	DefaultTTL = 5 * time.Minute
+)
+
+var (
+	// retryDelay is time between attempts to acquire lock held by someone else
+	retryDelay = 500 * time.Millisecond
+	// now returns current time, replaced in tests
+	now = time.Now
+)
+
+// Info describes owner of lock
+type Info struct {
+	ID      string    `json:"id"`
+	Owner   string    `json:"owner"`
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%s (pid %d on %s) since %s until %s", i.Owner, i.PID, i.Host,
+		i.Created.Format(time.RFC3339), i.Expires.Format(time.RFC3339))
+}
+
+// LockedError is returned when lock is held by someone else
+type LockedError struct {
+	Path   string
+	Holder Info
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s is locked by %s, if the owner is not running anymore remove the lock with force-unlock", e.Path, e.Holder)
+}
+
+// Options of acquired lock
+type Options struct {
+	// Owner describes who holds the lock, e.g. module and command
+	Owner string
+	// TTL is time after which lock expires, DefaultTTL when 0
+	TTL time.Duration
+	// Wait is how long to wait for lock held by someone else, error is returned immediately when 0
+	Wait time.Duration
+}
+
+// Lock is acquired lock of file
+type Lock struct {
+	path string
+	ttl  time.Duration
+	info Info
+}
+
+// Acquire locks file at path, returns LockedError when it is locked by someone else for longer
+// than options.Wait. Expired locks are taken over.
+func Acquire(path string, options Options) (*Lock, error) {
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	l := &Lock{path: path, ttl: options.TTL, info: Info{ID: id, Owner: options.Owner, PID: os.Getpid(), Host: host}}
+
+	deadline := now().Add(options.Wait)
+	for {
+		err := l.create()
+		if err == nil {
+			return l, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("cannot create lock of %s: %w", path, err)
+		}
+
+		holder, err := Read(path)
+		if err != nil {
+			return nil, err
+		}
+		if holder != nil && now().After(holder.Expires) {
+			// owner did not refresh the lock, it is most likely not running anymore
+			if err := removeIf(path, holder.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if holder != nil && !now().Before(deadline) {
+			return nil, &LockedError{Path: path, Holder: *holder}
+		}
+		time.Sleep(retryDelay)
+	}
+}
+
+// Info returns information stored in lock
+func (l *Lock) Info() Info {
+	return l.info
+}
+
+// Refresh extends expiration of lock, returns error when lock was taken over by someone else
+func (l *Lock) Refresh() error {
+	holder, err := Read(l.path)
+	if err != nil {
+		return err
+	}
+	if holder == nil || holder.ID != l.info.ID {
+		return fmt.Errorf("lock of %s was lost", l.path)
+	}
+	l.info.Expires = now().Add(l.ttl)
+	return l.write(os.O_WRONLY | os.O_TRUNC)
+}
+
+// Release removes lock, lock taken over by someone else is left as it is
+func (l *Lock) Release() error {
+	return removeIf(l.path, l.info.ID)
+}
+
+// Read returns information about holder of lock of file at path, nil if file is not locked
+func Read(path string) (*Info, error) {
+	info, err := readInfo(path + Suffix)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read lock of %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// reads lock file, nil if it does not exist
+func readInfo(lockPath string) (*Info, error) {
+	data, err := ioutil.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		// lock file is being written or is corrupted, treat it as held until it expires
+		info = Info{Owner: "unknown", Expires: modTime(lockPath).Add(DefaultTTL)}
+	}
+	return &info, nil
+}
+
+// ForceUnlock removes lock of file at path regardless of its owner, returns information about
+// removed lock, nil if file was not locked
+func ForceUnlock(path string) (*Info, error) {
+	info, err := Read(path)
+	if err != nil || info == nil {
+		return nil, err
+	}
+	if err := os.Remove(path + Suffix); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot remove lock of %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// creates lock file, fails with os.ErrExist when it exists
+func (l *Lock) create() error {
+	l.info.Created = now()
+	l.info.Expires = l.info.Created.Add(l.ttl)
+	return l.write(os.O_WRONLY | os.O_CREATE | os.O_EXCL)
+}
+
+func (l *Lock) write(flag int) error {
+	data, err := json.Marshal(l.info)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path+Suffix, flag, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// removes lock file when it still belongs to lock with id. Lock file is first renamed to a unique name, so
+// its content is checked after nobody can replace it anymore. Lock of someone else is put back unless a new
+// lock was created in the meantime, its owner then finds the lock lost on the next Refresh.
+func removeIf(path, id string) error {
+	suffix, err := newID()
+	if err != nil {
+		return err
+	}
+	stale := path + Suffix + "." + suffix
+	if err := os.Rename(path+Suffix, stale); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("cannot remove lock of %s: %w", path, err)
+	}
+	defer os.Remove(stale)
+
+	holder, err := readInfo(stale)
+	if err != nil {
+		return fmt.Errorf("cannot read lock of %s: %w", path, err)
+	}
+	if holder != nil && holder.ID != id {
+		// link does not replace lock created after rename, unlike rename back
+		if err := os.Link(stale, path+Suffix); err != nil && !os.IsExist(err) {
+			return fmt.Errorf("cannot restore lock of %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return now()
+	}
+	return info.ModTime()
+}
+
+func newID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
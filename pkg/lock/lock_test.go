@@ -0,0 +1,207 @@
+package lock
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func lockedPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "state.yml"), func() { os.RemoveAll(dir) }
+}
+
+func TestAcquireAndRelease(t *testing.T) {
+	// given
+	path, cleanup := lockedPath(t)
+	defer cleanup()
+
+	// when
+	l, err := Acquire(path, Options{Owner: "awsbi apply"})
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder, err := Read(path)
+	if err != nil || holder == nil {
+		t.Fatal("Expected lock to be held, got ", holder, err)
+	}
+	if holder.Owner != "awsbi apply" || holder.PID != os.Getpid() || holder.ID != l.Info().ID {
+		t.Error("Expected lock held by this process, got ", holder)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + Suffix); !os.IsNotExist(err) {
+		t.Error("Expected lock file to be removed, got ", err)
+	}
+}
+
+func TestAcquireLockedReportsHolder(t *testing.T) {
+	// given
+	path, cleanup := lockedPath(t)
+	defer cleanup()
+	held, err := Acquire(path, Options{Owner: "azbi apply"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Release()
+	retryDelay = time.Millisecond
+	defer func() { retryDelay = 500 * time.Millisecond }()
+
+	// when
+	_, err = Acquire(path, Options{Owner: "awsbi apply", Wait: 10 * time.Millisecond})
+
+	// then
+	locked, ok := err.(*LockedError)
+	if !ok {
+		t.Fatal("Expected LockedError, got ", err)
+	}
+	if locked.Holder.Owner != "azbi apply" || !strings.Contains(err.Error(), "azbi apply") {
+		t.Error("Expected error naming holder, got ", err)
+	}
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	// given
+	path, cleanup := lockedPath(t)
+	defer cleanup()
+	held, err := Acquire(path, Options{Owner: "azbi apply"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retryDelay = time.Millisecond
+	defer func() { retryDelay = 500 * time.Millisecond }()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		held.Release()
+	}()
+
+	// when
+	l, err := Acquire(path, Options{Owner: "awsbi apply", Wait: time.Minute})
+
+	// then
+	if err != nil {
+		t.Fatal("Expected lock to be acquired after release, got ", err)
+	}
+	l.Release()
+}
+
+func TestAcquireTakesOverExpiredLock(t *testing.T) {
+	// given
+	path, cleanup := lockedPath(t)
+	defer cleanup()
+	if _, err := Acquire(path, Options{Owner: "crashed", TTL: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	defer func() { now = time.Now }()
+
+	// when
+	l, err := Acquire(path, Options{Owner: "awsbi apply"})
+
+	// then
+	if err != nil {
+		t.Fatal("Expected expired lock to be taken over, got ", err)
+	}
+	if holder, _ := Read(path); holder == nil || holder.Owner != "awsbi apply" {
+		t.Error("Expected lock held by new owner, got ", holder)
+	}
+	l.Release()
+}
+
+func TestReleaseKeepsLockOfOthers(t *testing.T) {
+	// given
+	path, cleanup := lockedPath(t)
+	defer cleanup()
+	old, err := Acquire(path, Options{Owner: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ForceUnlock(path); err != nil {
+		t.Fatal(err)
+	}
+	current, err := Acquire(path, Options{Owner: "current"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer current.Release()
+
+	// when
+	err = old.Release()
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder, _ := Read(path); holder == nil || holder.Owner != "current" {
+		t.Error("Expected lock still held by current owner, got ", holder)
+	}
+	if err := old.Refresh(); err == nil {
+		t.Error("Expected refresh of lost lock to fail")
+	}
+}
+
+func TestConcurrentTakeOverOfExpiredLockHasSingleWinner(t *testing.T) {
+	// given
+	path, cleanup := lockedPath(t)
+	defer cleanup()
+	if _, err := Acquire(path, Options{Owner: "crashed", TTL: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	// new locks have to outlive shifted clock, so they are not taken over as expired
+	now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	defer func() { now = time.Now }()
+
+	// when
+	var wg sync.WaitGroup
+	locks := make(chan *Lock, 10)
+	for i := 0; i < cap(locks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l, err := Acquire(path, Options{Owner: "awsbi apply"}); err == nil {
+				locks <- l
+			}
+		}()
+	}
+	wg.Wait()
+	close(locks)
+
+	// then
+	held := 0
+	for l := range locks {
+		if l.Refresh() == nil {
+			held++
+		}
+	}
+	if held != 1 {
+		t.Error("Expected expired lock to be held by single new owner, got ", held)
+	}
+	files, _ := filepath.Glob(path + Suffix + ".*")
+	if len(files) != 0 {
+		t.Error("Expected no renamed lock files left, got ", files)
+	}
+}
+
+func TestForceUnlockOfUnlockedFile(t *testing.T) {
+	// given
+	path, cleanup := lockedPath(t)
+	defer cleanup()
+
+	// when
+	info, err := ForceUnlock(path)
+
+	// then
+	if info != nil || err != nil {
+		t.Error("Expected nothing to unlock, got ", info, err)
+	}
+}
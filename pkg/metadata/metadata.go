@@ -0,0 +1,42 @@
+// Package metadata reads module metadata printed by `metadata` command of the module.
+package metadata
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Dependencies are modules which have to be applied before this module. Strong dependency has to
+// be present in state file and applied. Weak dependency may be missing, but when it is present
+// it has to be applied.
+type Dependencies struct {
+	Strong []string `yaml:"strong"`
+	Weak   []string `yaml:"weak"`
+}
+
+// Metadata describes module
+type Metadata struct {
+	Labels       map[string]interface{} `yaml:"labels"`
+	Dependencies Dependencies           `yaml:"dependencies"`
+}
+
+// Parse parses module metadata
+func Parse(data []byte) (*Metadata, error) {
+	var m Metadata
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("cannot parse module metadata: %w", err)
+	}
+	if len(m.Labels) == 0 {
+		return nil, fmt.Errorf("module metadata has no labels")
+	}
+	return &m, nil
+}
+
+// Short returns short name of module, the key of its section in state file
+func (m *Metadata) Short() string {
+	if short, ok := m.Labels["short"].(string); ok {
+		return short
+	}
+	return ""
+}
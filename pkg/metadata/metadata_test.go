@@ -0,0 +1,47 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	// given
+	data := `labels:
+  version: 0.0.1
+  name: AWS Basic Infrastructure
+  short: awsbi
+  kind: infrastructure
+  provider: aws
+  provides-vms: true
+  provides-pubips: false
+dependencies:
+  strong: [azbi]
+  weak: []
+`
+
+	// when
+	m, err := Parse([]byte(data))
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Short() != "awsbi" {
+		t.Error("Expected short name awsbi, got ", m.Short())
+	}
+	expected := Dependencies{Strong: []string{"azbi"}, Weak: []string{}}
+	if !reflect.DeepEqual(m.Dependencies, expected) {
+		t.Error("Expected ", expected, " got ", m.Dependencies)
+	}
+}
+
+func TestParseWithoutLabels(t *testing.T) {
+	// when
+	_, err := Parse([]byte("dependencies: {}\n"))
+
+	// then
+	if err == nil {
+		t.Error("Expected error of metadata without labels")
+	}
+}
@@ -0,0 +1,192 @@
+// Package migration upgrades module config file and module section of state file written by older
+// module versions to the current layout. Every layout has a schema version and every Step upgrades
+// document from one version to the next one, so files of any older version are upgraded by
+// applying steps one after another. Steps work on YAML nodes, so comments and order of keys are
+// preserved.
+package migration
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+const versionKey = "schema_version"
+
+// Step upgrades document from version From to From+1
+type Step struct {
+	From        int
+	Description string
+	Apply       func(document *yaml.Node) error
+}
+
+// Migrator upgrades documents of one kind
+type Migrator struct {
+	Name    string
+	Current int
+	Steps   []Step
+	// Detect guesses version of document which has no schema version
+	Detect func(document *yaml.Node) int
+}
+
+// Result tells which steps were applied
+type Result struct {
+	From  int
+	To    int
+	Steps []Step
+	// Unversioned is set when document had no schema version and From was detected
+	Unversioned bool
+}
+
+// Changed tells if document was upgraded or got its schema version
+func (r Result) Changed() bool {
+	return r.From != r.To || r.Unversioned
+}
+
+// Migrate upgrades document (mapping node) in place to current version and sets its schema version
+func (m *Migrator) Migrate(document *yaml.Node) (Result, error) {
+	from, err := m.version(document)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{From: from, To: from, Unversioned: value(document, versionKey) == nil}
+	if from > m.Current {
+		return result, fmt.Errorf("%s has schema version %d, but this module supports versions up to %d, use newer module version", m.Name, from, m.Current)
+	}
+
+	for version := from; version < m.Current; version++ {
+		step, ok := m.step(version)
+		if !ok {
+			return result, fmt.Errorf("there is no migration of %s from schema version %d", m.Name, version)
+		}
+		if err := step.Apply(document); err != nil {
+			return result, fmt.Errorf("cannot migrate %s from schema version %d: %w", m.Name, version, err)
+		}
+		result.To = version + 1
+		result.Steps = append(result.Steps, step)
+	}
+	setVersion(document, m.Current)
+	return result, nil
+}
+
+// sets schema version of document, new key is placed after the first key (kind or status)
+func setVersion(document *yaml.Node, version int) {
+	node := scalar(strconv.Itoa(version), "!!int")
+	if old := value(document, versionKey); old != nil {
+		*old = *node
+		return
+	}
+	position := 0
+	if len(document.Content) >= 2 {
+		position = 2
+	}
+	content := append([]*yaml.Node{}, document.Content[:position]...)
+	content = append(content, scalar(versionKey, "!!str"), node)
+	document.Content = append(content, document.Content[position:]...)
+}
+
+// returns schema version of document, the detected one when it is missing
+func (m *Migrator) version(document *yaml.Node) (int, error) {
+	node := value(document, versionKey)
+	if node == nil {
+		return m.Detect(document), nil
+	}
+	version, err := strconv.Atoi(node.Value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("line %d: invalid schema version of %s: %q", node.Line, m.Name, node.Value)
+	}
+	return version, nil
+}
+
+func (m *Migrator) step(from int) (Step, bool) {
+	for _, step := range m.Steps {
+		if step.From == from {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
+// MigrateConfig upgrades content of config file, returns new content
+func MigrateConfig(data []byte) ([]byte, Result, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, Result{}, fmt.Errorf("cannot parse config file: %w", err)
+	}
+	if len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, Result{}, fmt.Errorf("config file has to be a mapping")
+	}
+	result, err := Config.Migrate(document.Content[0])
+	if err != nil {
+		return nil, result, err
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, result, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, result, err
+	}
+	return buffer.Bytes(), result, nil
+}
+
+// MigrateState upgrades module section of state file in place, state without module section is
+// left as it is
+func MigrateState(f *state.File) (Result, error) {
+	section := f.SectionNode(state.ModuleKey)
+	if section == nil {
+		return Result{}, nil
+	}
+	if section.Kind != yaml.MappingNode {
+		return Result{}, fmt.Errorf("%s section of state has to be a mapping", state.ModuleKey)
+	}
+	return State.Migrate(section)
+}
+
+// returns value node of key in mapping node, nil if there is no such key
+func value(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// appends key with value to mapping node unless the key is already there
+func setDefault(mapping *yaml.Node, key string, node *yaml.Node) {
+	if value(mapping, key) == nil {
+		mapping.Content = append(mapping.Content, scalar(key, "!!str"), node)
+	}
+}
+
+// renames key of mapping node, returns false if there is no such key
+func rename(mapping *yaml.Node, from, to string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == from {
+			mapping.Content[i].Value = to
+			return true
+		}
+	}
+	return false
+}
+
+func scalar(value, tag string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// parses YAML fragment into node
+func parse(fragment string) *yaml.Node {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(fragment), &document); err != nil {
+		panic(err)
+	}
+	return document.Content[0]
+}
@@ -0,0 +1,157 @@
+package migration
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// runs migrate on every testdata/<dir>/*.input.yml and compares result with .golden.yml file
+func golden(t *testing.T, dir string, migrate func(data []byte) ([]byte, error)) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", dir, "*.input.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("Expected test cases in testdata/", dir)
+	}
+	for _, input := range inputs {
+		goldenPath := strings.TrimSuffix(input, ".input.yml") + ".golden.yml"
+		t.Run(filepath.Base(goldenPath), func(t *testing.T) {
+			// given
+			data, err := ioutil.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// when
+			migrated, err := migrate(data)
+
+			// then
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *update {
+				if err := ioutil.WriteFile(goldenPath, migrated, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := ioutil.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal("Cannot read golden file, run tests with -update to create it: ", err)
+			}
+			if string(migrated) != string(expected) {
+				t.Error("Expected:\n", string(expected), "\ngot:\n", string(migrated))
+			}
+		})
+	}
+}
+
+func TestConfigGolden(t *testing.T) {
+	golden(t, "config", func(data []byte) ([]byte, error) {
+		migrated, _, err := MigrateConfig(data)
+		return migrated, err
+	})
+}
+
+func TestStateGolden(t *testing.T) {
+	golden(t, "state", func(data []byte) ([]byte, error) {
+		f, err := state.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := MigrateState(f); err != nil {
+			return nil, err
+		}
+		return f.Bytes()
+	})
+}
+
+func TestMigratedStateIsValid(t *testing.T) {
+	// given
+	data, err := ioutil.ReadFile("testdata/state/v1.input.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := state.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	result, err := MigrateState(f)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.From != 1 || result.To != state.SchemaVersion || len(result.Steps) != state.SchemaVersion-1 {
+		t.Error("Expected migration from 1 to ", state.SchemaVersion, " got ", result)
+	}
+	if err := f.Validate(); err != nil {
+		t.Error("Expected migrated state to be valid, got ", err)
+	}
+	section, err := f.AWSBI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section.Output == nil || len(section.Output.PublicSubnetIDs) != 1 || section.Output.PublicSubnetIDs[0] != "subnet-0a1b2c3d" {
+		t.Error("Expected public_subnet_id to become public_subnet_ids, got ", section.Output)
+	}
+}
+
+func TestNewerVersionIsRefused(t *testing.T) {
+	// given
+	data := []byte("kind: awsbi-config\nschema_version: 99\nawsbi:\n  name: epiphany\n")
+
+	// when
+	_, _, err := MigrateConfig(data)
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "use newer module version") {
+		t.Error("Expected error of unsupported version, got ", err)
+	}
+}
+
+func TestEveryVersionHasStep(t *testing.T) {
+	for _, m := range []*Migrator{Config, State} {
+		for version := 1; version < m.Current; version++ {
+			if _, ok := m.step(version); !ok {
+				t.Error("Expected migration of ", m.Name, " from version ", version)
+			}
+		}
+	}
+}
+
+func TestUnversionedFileIsChanged(t *testing.T) {
+	// given
+	unversioned := []byte("kind: awsbi-config\nawsbi:\n  name: epiphany\n  os: ubuntu\n")
+	current := []byte("kind: awsbi-config\nschema_version: 2\nawsbi:\n  name: epiphany\n  os: ubuntu\n")
+
+	// when
+	migrated, unversionedResult, err := MigrateConfig(unversioned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, currentResult, err := MigrateConfig(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	if !unversionedResult.Changed() || len(unversionedResult.Steps) != 0 {
+		t.Error("Expected unversioned file to get schema version without steps, got ", unversionedResult)
+	}
+	if string(migrated) != string(current) {
+		t.Error("Expected:\n", string(current), "\ngot:\n", string(migrated))
+	}
+	if currentResult.Changed() {
+		t.Error("Expected current file to stay unchanged, got ", currentResult)
+	}
+}
@@ -0,0 +1,80 @@
+package migration
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+// Config upgrades module config file (awsbi-config.yml)
+var Config = &Migrator{
+	Name:    "config file",
+	Current: state.ConfigSchemaVersion,
+	Steps: []Step{
+		{
+			From:        1,
+			Description: "add nat_gateway_count, subnets and os introduced in 0.0.2",
+			Apply: func(document *yaml.Node) error {
+				section := value(document, state.ModuleKey)
+				if section == nil || section.Kind != yaml.MappingNode {
+					return fmt.Errorf("there is no %s section", state.ModuleKey)
+				}
+				addParameters(section)
+				return nil
+			},
+		},
+	},
+	Detect: func(document *yaml.Node) int {
+		section := value(document, state.ModuleKey)
+		if section == nil || hasParameters(section) {
+			return state.ConfigSchemaVersion
+		}
+		return 1
+	},
+}
+
+// State upgrades module section of state file
+var State = &Migrator{
+	Name:    "state file",
+	Current: state.SchemaVersion,
+	Steps: []Step{
+		{
+			From:        1,
+			Description: "add nat_gateway_count, subnets and os introduced in 0.0.2, public_subnet_id output becomes public_subnet_ids",
+			Apply: func(section *yaml.Node) error {
+				if value(section, "name") != nil {
+					// module parameters are stored only after apply
+					addParameters(section)
+				}
+				if output := value(section, "output"); output != nil && output.Kind == yaml.MappingNode {
+					if subnet := value(output, "public_subnet_id"); subnet != nil && subnet.Kind == yaml.ScalarNode {
+						item := *subnet
+						*subnet = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{&item}}
+						rename(output, "public_subnet_id", "public_subnet_ids")
+					}
+				}
+				return nil
+			},
+		},
+	},
+	Detect: func(section *yaml.Node) int {
+		if value(section, "name") == nil || hasParameters(section) {
+			return state.SchemaVersion
+		}
+		return 1
+	},
+}
+
+// adds parameters introduced in 0.0.2 with values matching behaviour of 0.0.1
+func addParameters(section *yaml.Node) {
+	setDefault(section, "nat_gateway_count", scalar("1", "!!int"))
+	setDefault(section, "subnets", parse("private:\n  count: 1\npublic:\n  count: 1\n"))
+	setDefault(section, "os", scalar("ubuntu", "!!str"))
+}
+
+// tells if section has parameters introduced in 0.0.2
+func hasParameters(section *yaml.Node) bool {
+	return value(section, "subnets") != nil || value(section, "os") != nil || value(section, "nat_gateway_count") != nil
+}
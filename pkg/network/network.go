@@ -0,0 +1,199 @@
+// Package network computes network layout terraform creates for module parameters: CIDR blocks
+// and availability zones of subnets, subnets of NAT gateways and route tables of private subnets.
+// It mirrors resources/terraform/modules/ec2 (locals.tf and networking.tf), so layouts terraform
+// cannot create or which do not make sense are rejected before plan.
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+const (
+	// VPCCIDR is the CIDR block of module VPC (vpc_cidr_block of ec2 module)
+	VPCCIDR = "10.1.0.0/20"
+	// NewBits is the number of bits added to VPC prefix for every subnet (cidrsubnet in locals.tf)
+	NewBits = 4
+)
+
+// Subnet is single subnet of module VPC
+type Subnet struct {
+	Name string
+	CIDR string
+	Zone string
+	// RouteTable is the private route table of private subnet, routing through NAT gateway
+	RouteTable string
+}
+
+// NatGateway is NAT gateway placed in public subnet
+type NatGateway struct {
+	Name   string
+	Subnet string
+}
+
+// Instance is virtual machine placed in subnet
+type Instance struct {
+	Name   string
+	Subnet string
+}
+
+// Layout is network created by terraform for module parameters
+type Layout struct {
+	VPC         string
+	Zones       []string
+	Public      []Subnet
+	Private     []Subnet
+	NatGateways []NatGateway
+	Instances   []Instance
+}
+
+// Errors are all problems of layout
+type Errors []string
+
+func (e Errors) Error() string {
+	return "impossible network layout:\n" + strings.Join(e, "\n")
+}
+
+// Compute returns layout of network for module parameters and availability zones of region
+// (ordered by name as terraform orders them). Layout is returned together with Errors when it
+// cannot be created or does not make sense.
+func Compute(config state.Config, zones []string) (*Layout, error) {
+	_, vpc, err := net.ParseCIDR(VPCCIDR)
+	if err != nil {
+		return nil, err
+	}
+	public, private := config.Subnets.Public.Count, config.Subnets.Private.Count
+	prefix, _ := vpc.Mask.Size()
+
+	var errs Errors
+	if max := 1 << NewBits; public+private > max {
+		errs = append(errs, fmt.Sprintf("%d public and %d private subnets do not fit into VPC %s, at most %d subnets /%d are possible",
+			public, private, VPCCIDR, max, prefix+NewBits))
+	}
+	if public == 0 && config.UsePublicIP && config.InstanceCount > 0 {
+		errs = append(errs, "instances with public IP are placed in public subnets, but there are none")
+	}
+	if len(zones) == 0 {
+		errs = append(errs, fmt.Sprintf("region %s has no available zones", config.Region))
+	} else {
+		if public > len(zones) {
+			errs = append(errs, fmt.Sprintf("%d public subnets are more than %d availability zones of region %s (%s)",
+				public, len(zones), config.Region, strings.Join(zones, ", ")))
+		}
+		if private > len(zones) {
+			errs = append(errs, fmt.Sprintf("%d private subnets are more than %d availability zones of region %s (%s)",
+				private, len(zones), config.Region, strings.Join(zones, ", ")))
+		}
+	}
+	if config.NatGatewayCount > public {
+		errs = append(errs, fmt.Sprintf("%d NAT gateways are more than %d public subnets they are placed in", config.NatGatewayCount, public))
+	}
+	if private > 0 && config.NatGatewayCount == 0 {
+		errs = append(errs, "private subnets need at least one NAT gateway to route through")
+	}
+	if config.InstanceCount > 0 && !config.UsePublicIP && private == 0 {
+		errs = append(errs, "instances without public IP are placed in private subnets, but there are none")
+	}
+	if len(errs) > 0 && (public+private > 1<<NewBits || len(zones) == 0) {
+		// there is no layout to show
+		return nil, errs
+	}
+
+	layout := &Layout{VPC: VPCCIDR, Zones: zones}
+	for i := 0; i < public; i++ {
+		layout.Public = append(layout.Public, Subnet{
+			Name: fmt.Sprintf("%s-subnet-public%d", config.Name, i),
+			CIDR: subnet(vpc, i),
+			Zone: zones[i%len(zones)],
+		})
+	}
+	for i := 0; i < private; i++ {
+		s := Subnet{
+			Name: fmt.Sprintf("%s-subnet-private%d", config.Name, i),
+			CIDR: subnet(vpc, public+i),
+			Zone: zones[i%len(zones)],
+		}
+		if config.NatGatewayCount > 0 {
+			s.RouteTable = fmt.Sprintf("%s-rt-private%d", config.Name, i%config.NatGatewayCount)
+		}
+		layout.Private = append(layout.Private, s)
+	}
+	for i := 0; i < config.NatGatewayCount && public > 0; i++ {
+		layout.NatGateways = append(layout.NatGateways, NatGateway{
+			Name:   fmt.Sprintf("%s-ng%d", config.Name, i),
+			Subnet: layout.Public[i%public].Name,
+		})
+	}
+	subnets := layout.Private
+	if config.UsePublicIP {
+		subnets = layout.Public
+	}
+	for i := 0; i < config.InstanceCount && len(subnets) > 0; i++ {
+		layout.Instances = append(layout.Instances, Instance{
+			Name:   fmt.Sprintf("%s-instance%d", config.Name, i),
+			Subnet: subnets[i%len(subnets)].Name,
+		})
+	}
+
+	if len(errs) > 0 {
+		return layout, errs
+	}
+	return layout, nil
+}
+
+// Text returns layout in human readable form
+func (l *Layout) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "VPC %s, availability zones: %s\n", l.VPC, strings.Join(l.Zones, ", "))
+	for _, s := range l.Public {
+		fmt.Fprintf(&b, "  public  %-18s %-16s %s\n", s.CIDR, s.Zone, s.Name)
+	}
+	for _, s := range l.Private {
+		route := "no route to internet"
+		if s.RouteTable != "" {
+			route = "routed by " + s.RouteTable
+		}
+		fmt.Fprintf(&b, "  private %-18s %-16s %s (%s)\n", s.CIDR, s.Zone, s.Name, route)
+	}
+	for _, n := range l.NatGateways {
+		fmt.Fprintf(&b, "  NAT gateway %s in %s\n", n.Name, n.Subnet)
+	}
+	for _, i := range l.Instances {
+		fmt.Fprintf(&b, "  instance %s in %s\n", i.Name, i.Subnet)
+	}
+	return b.String()
+}
+
+// Zones returns names of available zones of region of EC2 client, ordered by name
+func Zones(client ec2iface.EC2API) ([]string, error) {
+	output, err := client.DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("state"), Values: []*string{aws.String("available")}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list availability zones: %w", err)
+	}
+	var zones []string
+	for _, zone := range output.AvailabilityZones {
+		zones = append(zones, aws.StringValue(zone.ZoneName))
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+// returns CIDR block of subnet with number, the same as cidrsubnet(vpc, NewBits, number)
+func subnet(vpc *net.IPNet, number int) string {
+	prefix, bits := vpc.Mask.Size()
+	base := binary.BigEndian.Uint32(vpc.IP.To4())
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, base+uint32(number)<<uint(bits-prefix-NewBits))
+	return fmt.Sprintf("%s/%d", ip, prefix+NewBits)
+}
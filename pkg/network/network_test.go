@@ -0,0 +1,173 @@
+package network
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+var zones = []string{"eu-central-1a", "eu-central-1b", "eu-central-1c"}
+
+func config(public, private, nat, instances int) state.Config {
+	return state.Config{
+		Name:            "epiphany",
+		InstanceCount:   instances,
+		Region:          "eu-central-1",
+		NatGatewayCount: nat,
+		Subnets:         state.Subnets{Private: state.SubnetGroup{Count: private}, Public: state.SubnetGroup{Count: public}},
+		OS:              "ubuntu",
+	}
+}
+
+func TestCompute(t *testing.T) {
+	// when
+	layout, err := Compute(config(2, 3, 2, 4), zones)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `VPC 10.1.0.0/20, availability zones: eu-central-1a, eu-central-1b, eu-central-1c
+  public  10.1.0.0/24        eu-central-1a    epiphany-subnet-public0
+  public  10.1.1.0/24        eu-central-1b    epiphany-subnet-public1
+  private 10.1.2.0/24        eu-central-1a    epiphany-subnet-private0 (routed by epiphany-rt-private0)
+  private 10.1.3.0/24        eu-central-1b    epiphany-subnet-private1 (routed by epiphany-rt-private1)
+  private 10.1.4.0/24        eu-central-1c    epiphany-subnet-private2 (routed by epiphany-rt-private0)
+  NAT gateway epiphany-ng0 in epiphany-subnet-public0
+  NAT gateway epiphany-ng1 in epiphany-subnet-public1
+  instance epiphany-instance0 in epiphany-subnet-private0
+  instance epiphany-instance1 in epiphany-subnet-private1
+  instance epiphany-instance2 in epiphany-subnet-private2
+  instance epiphany-instance3 in epiphany-subnet-private0
+`
+	if layout.Text() != expected {
+		t.Error("Expected:\n", expected, "\ngot:\n", layout.Text())
+	}
+}
+
+func TestLastSubnetFitsIntoVPC(t *testing.T) {
+	// given
+	var many []string
+	for i := 0; i < 16; i++ {
+		many = append(many, fmt.Sprintf("zone%02d", i))
+	}
+
+	// when
+	layout, err := Compute(config(1, 15, 1, 0), many)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.Private[14].CIDR != "10.1.15.0/24" {
+		t.Error("Expected the last subnet to be 10.1.15.0/24, got ", layout.Private[14].CIDR)
+	}
+}
+
+func withPublicIP(config state.Config) state.Config {
+	config.UsePublicIP = true
+	return config
+}
+
+func TestLayoutsWithoutPublicSubnets(t *testing.T) {
+	tests := []state.Config{
+		config(0, 0, 0, 0),
+		withPublicIP(config(0, 0, 0, 0)),
+	}
+	for _, test := range tests {
+		// when
+		layout, err := Compute(test, zones)
+
+		// then
+		if err != nil {
+			t.Error("Expected no problems for ", test.Subnets, " with use_public_ip ", test.UsePublicIP, " got ", err)
+			continue
+		}
+		if len(layout.Public) != 0 || len(layout.NatGateways) != 0 {
+			t.Error("Expected no public subnets and NAT gateways, got ", layout.Text())
+		}
+	}
+}
+
+func TestImpossibleLayouts(t *testing.T) {
+	tests := []struct {
+		config   state.Config
+		problems []string
+	}{
+		{config(10, 7, 1, 1), []string{"do not fit into VPC 10.1.0.0/20, at most 16 subnets /24", "10 public subnets", "7 private subnets"}},
+		{config(0, 1, 0, 1), []string{"NAT gateway to route through"}},
+		{withPublicIP(config(0, 0, 0, 1)), []string{"instances with public IP are placed in public subnets"}},
+		{config(0, 1, 1, 1), []string{"1 NAT gateways are more than 0 public subnets"}},
+		{config(1, 0, 1, 1), []string{"instances without public IP"}},
+		{config(4, 1, 1, 1), []string{"4 public subnets are more than 3 availability zones"}},
+		{config(1, 4, 1, 1), []string{"4 private subnets are more than 3 availability zones"}},
+		{config(1, 1, 2, 1), []string{"2 NAT gateways are more than 1 public subnets"}},
+	}
+	for _, test := range tests {
+		// when
+		_, err := Compute(test.config, zones)
+
+		// then
+		errs, ok := err.(Errors)
+		if !ok || len(errs) != len(test.problems) {
+			t.Error("Expected ", len(test.problems), " problems, got ", err)
+			continue
+		}
+		for i, problem := range test.problems {
+			if !strings.Contains(errs[i], problem) {
+				t.Error("Expected problem ", problem, " got ", errs[i])
+			}
+		}
+	}
+}
+
+func TestConstantsMatchTerraform(t *testing.T) {
+	// given
+	variables, err := ioutil.ReadFile("../../resources/terraform/modules/ec2/variables.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	locals, err := ioutil.ReadFile("../../resources/terraform/modules/ec2/locals.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	if !strings.Contains(string(variables), `default     = "`+VPCCIDR+`"`) {
+		t.Error("Expected VPC CIDR ", VPCCIDR, " in ec2 module variables")
+	}
+	if !strings.Contains(string(locals), "cidrsubnet(var.vpc_cidr_block, 4, num)") || NewBits != 4 {
+		t.Error("Expected subnets to be carved with 4 new bits in ec2 module locals")
+	}
+}
+
+type zonesClient struct {
+	ec2iface.EC2API
+}
+
+func (zonesClient) DescribeAvailabilityZones(input *ec2.DescribeAvailabilityZonesInput) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	return &ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{
+		{ZoneName: aws.String("eu-central-1b")},
+		{ZoneName: aws.String("eu-central-1a")},
+	}}, nil
+}
+
+func TestZonesAreOrderedByName(t *testing.T) {
+	// when
+	result, err := Zones(zonesClient{})
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(result, ",") != "eu-central-1a,eu-central-1b" {
+		t.Error("Expected zones ordered by name, got ", result)
+	}
+}
@@ -0,0 +1,154 @@
+// Package policy checks terraform plan of module (JSON format of `terraform show -json`) against
+// rules of safe infrastructure: no ingress open to the whole internet, no public IPs of instances,
+// root volumes within limits, allowed regions and operating systems and mandatory tags. Every rule
+// has severity, violations of rules with error severity block apply.
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity of rule tells what its violation means
+type Severity string
+
+const (
+	// Error violations block apply
+	Error Severity = "error"
+	// Warning violations are only reported
+	Warning Severity = "warning"
+	// Off rules are not checked
+	Off Severity = "off"
+)
+
+// Rule names
+const (
+	WorldOpenIngress = "world-open-ingress"
+	PublicIP         = "public-ip"
+	RootVolumeSize   = "root-volume-size"
+	AllowedRegions   = "allowed-regions"
+	AllowedOS        = "allowed-os"
+	MandatoryTags    = "mandatory-tags"
+)
+
+// Limits are inclusive bounds of number, zero Max means no upper bound
+type Limits struct {
+	Min int `yaml:"min" json:"min"`
+	Max int `yaml:"max" json:"max"`
+}
+
+func (l Limits) String() string {
+	if l.Max == 0 {
+		return fmt.Sprintf("at least %d GiB", l.Min)
+	}
+	return fmt.Sprintf("%d-%d GiB", l.Min, l.Max)
+}
+
+// Policy is set of rules with their parameters
+type Policy struct {
+	// Rules are severities of rules by name, rules which are not listed keep default severity
+	Rules map[string]Severity `yaml:"rules" json:"rules"`
+	// AllowedRegions are regions module may be applied in, any region is allowed when empty
+	AllowedRegions []string `yaml:"allowed_regions" json:"allowed_regions"`
+	// AllowedOS are operating systems of instances (os parameter), any is allowed when empty
+	AllowedOS []string `yaml:"allowed_os" json:"allowed_os"`
+	// RootVolumeSize are limits of root volume size of instances in GiB
+	RootVolumeSize Limits `yaml:"root_volume_size" json:"root_volume_size"`
+	// MandatoryTags are tags every taggable resource has to have
+	MandatoryTags []string `yaml:"mandatory_tags" json:"mandatory_tags"`
+}
+
+// Default returns policy used when there is no policy file. Violations of all rules except public
+// IPs block apply, environments which need e.g. SSH open to the whole internet lower severity of
+// the rule to warning in policy file.
+func Default() *Policy {
+	return &Policy{
+		Rules: map[string]Severity{
+			WorldOpenIngress: Error,
+			PublicIP:         Warning,
+			RootVolumeSize:   Error,
+			AllowedRegions:   Error,
+			AllowedOS:        Error,
+			MandatoryTags:    Error,
+		},
+		AllowedOS:      []string{"redhat", "ubuntu"},
+		RootVolumeSize: Limits{Min: 8, Max: 1024},
+		MandatoryTags:  []string{"resource_group"},
+	}
+}
+
+// Load reads policy file (YAML or JSON), values which are not set in file keep defaults. Default
+// policy is returned when path is empty.
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read policy file: %w", err)
+	}
+	policy, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("policy file %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Parse reads policy from content of policy file over defaults and checks it
+func Parse(data []byte) (*Policy, error) {
+	policy := Default()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// empty file keeps defaults
+	if err := decoder.Decode(policy); err != nil && len(bytes.TrimSpace(data)) > 0 {
+		return nil, err
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// returns error listing every unknown rule, severity and wrong limits
+func (p *Policy) validate() error {
+	var problems []string
+	for _, name := range sortedRules(p.Rules) {
+		if _, ok := Default().Rules[name]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown rule %s", name))
+		}
+		switch severity := p.Rules[name]; severity {
+		case Error, Warning, Off:
+		default:
+			problems = append(problems, fmt.Sprintf("rule %s has severity %q, expected error, warning or off", name, severity))
+		}
+	}
+	if p.RootVolumeSize.Min < 0 || (p.RootVolumeSize.Max > 0 && p.RootVolumeSize.Max < p.RootVolumeSize.Min) {
+		problems = append(problems, fmt.Sprintf("root_volume_size limits %d-%d are wrong", p.RootVolumeSize.Min, p.RootVolumeSize.Max))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid policy:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// Severity returns severity of rule
+func (p *Policy) Severity(rule string) Severity {
+	if severity, ok := p.Rules[rule]; ok {
+		return severity
+	}
+	return Default().Rules[rule]
+}
+
+func sortedRules(rules map[string]Severity) []string {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,186 @@
+package policy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func loadPlan(t *testing.T) []byte {
+	data, err := ioutil.ReadFile("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDefaultPolicyBlocksApply(t *testing.T) {
+	// when
+	report, err := Default().Evaluate(loadPlan(t))
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Violation{
+		{MandatoryTags, Error, "module.ec2.aws_resourcegroups_group.rg", "missing tags resource_group"},
+		{WorldOpenIngress, Error, "module.ec2.aws_security_group.awsbi_security_group", "ingress tcp port 22 is open to 0.0.0.0/0"},
+	}
+	if len(report.Violations) != len(expected) {
+		t.Fatal("Expected ", expected, " got ", report.Violations)
+	}
+	for i := range expected {
+		if report.Violations[i] != expected[i] {
+			t.Error("Expected ", expected[i], " got ", report.Violations[i])
+		}
+	}
+	if !report.Blocking() {
+		t.Error("Expected world-open ingress and missing tags to block apply")
+	}
+}
+
+func TestWarningsDoNotBlockApply(t *testing.T) {
+	// given
+	policy, err := Parse([]byte(`
+rules:
+  world-open-ingress: warning
+  mandatory-tags: warning
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	report, err := policy.Evaluate(loadPlan(t))
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Blocking() || report.Count(Warning) != 2 {
+		t.Error("Expected 2 warnings not blocking apply, got ", report.Violations)
+	}
+}
+
+func TestProductionPolicyBlocksApply(t *testing.T) {
+	// given
+	policy, err := Parse([]byte(`
+rules:
+  world-open-ingress: error
+  mandatory-tags: off
+allowed_regions: [eu-west-1, eu-west-2]
+root_volume_size:
+  max: 50
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	report, err := policy.Evaluate(loadPlan(t))
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"error allowed-regions var.region: eu-central-1 is not allowed, allowed are eu-west-1, eu-west-2",
+		"error world-open-ingress module.ec2.aws_security_group.awsbi_security_group: ingress tcp port 22 is open to 0.0.0.0/0",
+		"error root-volume-size module.ec2.aws_instance.awsbi[0]: root volume of 64 GiB is out of limits (8-50 GiB)",
+	}
+	var actual []string
+	for _, v := range report.Violations {
+		actual = append(actual, string(v.Severity)+" "+v.Rule+" "+v.Address+": "+v.Message)
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Error("Expected:\n", strings.Join(expected, "\n"), "\ngot:\n", strings.Join(actual, "\n"))
+	}
+	if !report.Blocking() || report.Count(Error) != 3 {
+		t.Error("Expected 3 errors blocking apply, got ", report.Count(Error))
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	// given
+	plan := []byte(`{"resource_changes": [{"address": "aws_instance.vm", "type": "aws_instance", "change": {"actions": ["update"],
+		"after": {"associate_public_ip_address": true, "root_block_device": [{"volume_size": null}]}}}]}`)
+
+	// when
+	report, err := Default().Evaluate(plan)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Violations) != 1 || report.Violations[0].Rule != PublicIP {
+		t.Error("Expected public IP violation, got ", report.Violations)
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	// when
+	policy, err := Parse([]byte(`allowed_os: [ubuntu]`))
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Severity(AllowedOS) != Error || policy.Severity(PublicIP) != Warning {
+		t.Error("Expected default severities, got ", policy.Rules)
+	}
+	if len(policy.AllowedOS) != 1 || policy.RootVolumeSize != (Limits{Min: 8, Max: 1024}) {
+		t.Error("Expected allowed OS from file and default limits, got ", policy)
+	}
+}
+
+func TestParseReportsAllProblems(t *testing.T) {
+	// when
+	_, err := Parse([]byte(`
+rules:
+  open-ingress: error
+  public-ip: fatal
+root_volume_size: {min: 100, max: 10}
+`))
+
+	// then
+	expected := `invalid policy:
+unknown rule open-ingress
+rule public-ip has severity "fatal", expected error, warning or off
+root_volume_size limits 100-10 are wrong`
+	if err == nil || err.Error() != expected {
+		t.Error("Expected:\n", expected, "\ngot:\n", err)
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	// when
+	_, err := Parse([]byte(`mandatory_tag: [owner]`))
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "mandatory_tag") {
+		t.Error("Expected error of unknown field, got ", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	// given
+	report := &Report{Violations: []Violation{
+		{WorldOpenIngress, Error, "aws_security_group.sg", "ingress tcp port 22 is open to 0.0.0.0/0"},
+		{PublicIP, Warning, "aws_instance.vm[0]", "instance gets public IP address"},
+	}}
+	var b bytes.Buffer
+
+	// when
+	report.Print(&b)
+
+	// then
+	expected := `SEVERITY  RULE                ADDRESS                MESSAGE
+error     world-open-ingress  aws_security_group.sg  ingress tcp port 22 is open to 0.0.0.0/0
+warning   public-ip           aws_instance.vm[0]     instance gets public IP address
+Errors: 1, warnings: 1
+`
+	if b.String() != expected {
+		t.Error("Expected:\n", expected, "got:\n", b.String())
+	}
+}
@@ -0,0 +1,213 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Violation is single breach of rule by planned resource or module parameter
+type Violation struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Address is terraform address of resource (or var.<name> of variable)
+	Address string `json:"address"`
+	Message string `json:"message"`
+}
+
+// Report holds violations of plan in order of rules and resources in plan
+type Report struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Count returns number of violations with severity
+func (r *Report) Count(severity Severity) int {
+	count := 0
+	for _, violation := range r.Violations {
+		if violation.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Blocking returns true if plan cannot be applied
+func (r *Report) Blocking() bool {
+	return r.Count(Error) > 0
+}
+
+// Print writes report in tabular form
+func (r *Report) Print(w io.Writer) {
+	if len(r.Violations) == 0 {
+		fmt.Fprintln(w, "Plan complies with policy")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tRULE\tADDRESS\tMESSAGE")
+	for _, v := range r.Violations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", v.Severity, v.Rule, v.Address, v.Message)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Errors: %d, warnings: %d\n", r.Count(Error), r.Count(Warning))
+}
+
+// plan is the part of `terraform show -json` output rules are checked against
+type plan struct {
+	Variables map[string]struct {
+		Value interface{} `json:"value"`
+	} `json:"variables"`
+	ResourceChanges []resource `json:"resource_changes"`
+}
+
+type resource struct {
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Change  struct {
+		Actions      []string               `json:"actions"`
+		After        map[string]interface{} `json:"after"`
+		AfterUnknown map[string]interface{} `json:"after_unknown"`
+	} `json:"change"`
+}
+
+// Evaluate checks plan in JSON format of `terraform show -json` against rules of policy, resources
+// which are only destroyed or do not change are not checked
+func (p *Policy) Evaluate(data []byte) (*Report, error) {
+	var document plan
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("cannot parse terraform plan: %w", err)
+	}
+
+	e := &evaluation{policy: p, report: &Report{Violations: []Violation{}}}
+	e.variable(AllowedRegions, document, "region", p.AllowedRegions)
+	e.variable(AllowedOS, document, "os", p.AllowedOS)
+	for _, r := range document.ResourceChanges {
+		if r.Mode == "data" || r.Change.After == nil || !changes(r.Change.Actions) {
+			continue
+		}
+		switch r.Type {
+		case "aws_security_group":
+			for _, rule := range list(r.Change.After["ingress"]) {
+				fields, _ := rule.(map[string]interface{})
+				e.ingress(r.Address, fields)
+			}
+		case "aws_security_group_rule":
+			if r.Change.After["type"] == "ingress" {
+				e.ingress(r.Address, r.Change.After)
+			}
+		case "aws_instance":
+			e.instance(r.Address, r.Change.After)
+		}
+		e.tags(r)
+	}
+	return e.report, nil
+}
+
+// evaluation collects violations of rules which are not turned off
+type evaluation struct {
+	policy *Policy
+	report *Report
+}
+
+func (e *evaluation) violated(rule, address, format string, args ...interface{}) {
+	severity := e.policy.Severity(rule)
+	if severity == Off {
+		return
+	}
+	e.report.Violations = append(e.report.Violations, Violation{
+		Rule:     rule,
+		Severity: severity,
+		Address:  address,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// checks that value of variable is one of allowed ones
+func (e *evaluation) variable(rule string, document plan, name string, allowed []string) {
+	variable, ok := document.Variables[name]
+	if !ok || len(allowed) == 0 {
+		return
+	}
+	value := fmt.Sprint(variable.Value)
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	e.violated(rule, "var."+name, "%s is not allowed, allowed are %s", value, strings.Join(allowed, ", "))
+}
+
+// checks that ingress rule is not open to the whole internet
+func (e *evaluation) ingress(address string, rule map[string]interface{}) {
+	var open []string
+	for _, key := range []string{"cidr_blocks", "ipv6_cidr_blocks"} {
+		for _, block := range list(rule[key]) {
+			if block == "0.0.0.0/0" || block == "::/0" {
+				open = append(open, block.(string))
+			}
+		}
+	}
+	if len(open) == 0 {
+		return
+	}
+	ports := fmt.Sprintf("ports %v-%v", rule["from_port"], rule["to_port"])
+	if rule["from_port"] == rule["to_port"] {
+		ports = fmt.Sprintf("port %v", rule["from_port"])
+	}
+	e.violated(WorldOpenIngress, address, "ingress %v %s is open to %s", rule["protocol"], ports, strings.Join(open, ", "))
+}
+
+// checks public IP and root volume of instance
+func (e *evaluation) instance(address string, after map[string]interface{}) {
+	if after["associate_public_ip_address"] == true {
+		e.violated(PublicIP, address, "instance gets public IP address")
+	}
+	limits := e.policy.RootVolumeSize
+	for _, device := range list(after["root_block_device"]) {
+		fields, _ := device.(map[string]interface{})
+		size, ok := fields["volume_size"].(float64)
+		if !ok {
+			// size is not known until apply
+			continue
+		}
+		if int(size) < limits.Min || (limits.Max > 0 && int(size) > limits.Max) {
+			e.violated(RootVolumeSize, address, "root volume of %v GiB is out of limits (%s)", size, limits)
+		}
+	}
+}
+
+// checks mandatory tags of resource, resources without tags attribute cannot be tagged
+func (e *evaluation) tags(r resource) {
+	value, taggable := r.Change.After["tags"]
+	if !taggable || r.Change.AfterUnknown["tags"] == true {
+		return
+	}
+	tags, _ := value.(map[string]interface{})
+	var missing []string
+	for _, tag := range e.policy.MandatoryTags {
+		if _, ok := tags[tag]; !ok {
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) > 0 {
+		e.violated(MandatoryTags, r.Address, "missing tags %s", strings.Join(missing, ", "))
+	}
+}
+
+// tells if actions create or update resource
+func changes(actions []string) bool {
+	for _, action := range actions {
+		if action == "create" || action == "update" {
+			return true
+		}
+	}
+	return false
+}
+
+// returns elements of JSON array, nested blocks of resources are arrays of objects
+func list(value interface{}) []interface{} {
+	elements, _ := value.([]interface{})
+	return elements
+}
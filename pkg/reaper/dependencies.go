@@ -0,0 +1,227 @@
+package reaper
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// addDependencies describes resources from graph and adds dependencies between them:
+//   - instance uses its security groups and subnet
+//   - nat gateway uses its elastic IPs and subnet
+//   - internet gateway uses VPC it is attached to
+//   - route table uses subnets it is associated with, main route table is excluded as it is removed with its VPC
+//   - subnet and security group use their VPC
+//   - network interface uses its subnet and security groups
+//   - network interface and volume retained after termination are used by their instance until it is terminated
+//   - instances and nat gateways use internet gateway of their VPC, as they hold mapped public addresses
+func addDependencies(ec2Client ec2iface.EC2API, g *graph) error {
+
+	igwsByVpc := make(map[string][]string)
+	for _, filter := range idFilters("internet-gateway-id", g.idsOfType("InternetGateway")) {
+		err := ec2Client.DescribeInternetGatewaysPages(&ec2.DescribeInternetGatewaysInput{
+			Filters: []*ec2.Filter{filter},
+		}, func(out *ec2.DescribeInternetGatewaysOutput, lastPage bool) bool {
+			for _, igw := range out.InternetGateways {
+				for _, attachment := range igw.Attachments {
+					g.addDependency(*igw.InternetGatewayId, *attachment.VpcId)
+					igwsByVpc[*attachment.VpcId] = append(igwsByVpc[*attachment.VpcId], *igw.InternetGatewayId)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("Internet Gateway: Describing internet gateways error: %w", err)
+		}
+	}
+
+	for _, filter := range idFilters("instance-id", g.idsOfType("Instance")) {
+		err := ec2Client.DescribeInstancesPages(&ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{filter},
+		}, func(out *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range out.Reservations {
+				for _, instance := range reservation.Instances {
+					for _, sg := range instance.SecurityGroups {
+						g.addDependency(*instance.InstanceId, *sg.GroupId)
+					}
+					if instance.SubnetId != nil {
+						g.addDependency(*instance.InstanceId, *instance.SubnetId)
+					}
+					if instance.VpcId != nil {
+						for _, igw := range igwsByVpc[*instance.VpcId] {
+							g.addDependency(*instance.InstanceId, igw)
+						}
+					}
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("EC2: Describe error: %w", err)
+		}
+	}
+
+	for _, filter := range idFilters("nat-gateway-id", g.idsOfType("NatGateway")) {
+		err := ec2Client.DescribeNatGatewaysPages(&ec2.DescribeNatGatewaysInput{
+			Filter: []*ec2.Filter{filter},
+		}, func(out *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+			for _, ng := range out.NatGateways {
+				for _, address := range ng.NatGatewayAddresses {
+					if address.AllocationId != nil {
+						g.addDependency(*ng.NatGatewayId, *address.AllocationId)
+					}
+				}
+				// subnet and VPC of failed or deleted nat gateway can be missing
+				if subnetID := aws.StringValue(ng.SubnetId); subnetID != "" {
+					g.addDependency(*ng.NatGatewayId, subnetID)
+				}
+				if vpcID := aws.StringValue(ng.VpcId); vpcID != "" {
+					for _, igw := range igwsByVpc[vpcID] {
+						g.addDependency(*ng.NatGatewayId, igw)
+					}
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("Nat Gateway: Describe error: %w", err)
+		}
+	}
+
+	for _, filter := range idFilters("route-table-id", g.idsOfType("RouteTable")) {
+		err := ec2Client.DescribeRouteTablesPages(&ec2.DescribeRouteTablesInput{
+			Filters: []*ec2.Filter{filter},
+		}, func(out *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+			for _, rt := range out.RouteTables {
+				if isMainRouteTable(rt) {
+					g.exclude(*rt.RouteTableId)
+					continue
+				}
+				for _, association := range rt.Associations {
+					if association.SubnetId != nil {
+						g.addDependency(*rt.RouteTableId, *association.SubnetId)
+					}
+				}
+				g.addDependency(*rt.RouteTableId, *rt.VpcId)
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("RouteTable: Describing route tables error: %w", err)
+		}
+	}
+
+	for _, filter := range idFilters("subnet-id", g.idsOfType("Subnet")) {
+		err := ec2Client.DescribeSubnetsPages(&ec2.DescribeSubnetsInput{
+			Filters: []*ec2.Filter{filter},
+		}, func(out *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+			for _, subnet := range out.Subnets {
+				g.addDependency(*subnet.SubnetId, *subnet.VpcId)
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("Subnet: Describing subnets error: %w", err)
+		}
+	}
+
+	for _, filter := range idFilters("group-id", g.idsOfType("SecurityGroup")) {
+		err := ec2Client.DescribeSecurityGroupsPages(&ec2.DescribeSecurityGroupsInput{
+			Filters: []*ec2.Filter{filter},
+		}, func(out *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+			for _, sg := range out.SecurityGroups {
+				g.addDependency(*sg.GroupId, *sg.VpcId)
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("Security Group: Describing security groups error: %w", err)
+		}
+	}
+
+	for _, filter := range idFilters("network-interface-id", g.idsOfType("NetworkInterface")) {
+		err := ec2Client.DescribeNetworkInterfacesPages(&ec2.DescribeNetworkInterfacesInput{
+			Filters: []*ec2.Filter{filter},
+		}, func(out *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+			for _, eni := range out.NetworkInterfaces {
+				g.addDependency(*eni.NetworkInterfaceId, aws.StringValue(eni.SubnetId))
+				for _, sg := range eni.Groups {
+					g.addDependency(*eni.NetworkInterfaceId, aws.StringValue(sg.GroupId))
+				}
+				if eni.Attachment != nil {
+					g.addDependency(aws.StringValue(eni.Attachment.InstanceId), *eni.NetworkInterfaceId)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("Network Interface: Describing network interfaces error: %w", err)
+		}
+	}
+
+	for _, filter := range idFilters("volume-id", g.idsOfType("Volume")) {
+		err := ec2Client.DescribeVolumesPages(&ec2.DescribeVolumesInput{
+			Filters: []*ec2.Filter{filter},
+		}, func(out *ec2.DescribeVolumesOutput, lastPage bool) bool {
+			for _, volume := range out.Volumes {
+				for _, attachment := range volume.Attachments {
+					g.addDependency(aws.StringValue(attachment.InstanceId), *volume.VolumeId)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("Volume: Describing volumes error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// idsOfType returns IDs of all resources of given type in the graph
+func (g *graph) idsOfType(resourceType string) []string {
+	ids := make([]string, 0)
+	for id, n := range g.nodes {
+		if n.resource.Type == resourceType {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// maxFilterValues is the maximum number of values of single describe filter accepted by EC2
+const maxFilterValues = 200
+
+// idFilter creates describe filter matching any of the IDs, unlike describing by IDs it does not fail
+// when one of the resources does not exist anymore
+func idFilter(name string, ids []string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String(name),
+		Values: aws.StringSlice(ids),
+	}
+}
+
+// idFilters creates describe filters matching IDs in batches of at most maxFilterValues IDs, every
+// filter has to be described separately, no filter is returned when there are no IDs
+func idFilters(name string, ids []string) []*ec2.Filter {
+	var filters []*ec2.Filter
+	for start := 0; start < len(ids); start += maxFilterValues {
+		end := start + maxFilterValues
+		if end > len(ids) {
+			end = len(ids)
+		}
+		filters = append(filters, idFilter(name, ids[start:end]))
+	}
+	return filters
+}
+
+func isMainRouteTable(rt *ec2.RouteTable) bool {
+	for _, association := range rt.Associations {
+		if aws.BoolValue(association.Main) {
+			return true
+		}
+	}
+	return false
+}
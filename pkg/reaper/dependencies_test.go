@@ -0,0 +1,67 @@
+package reaper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/awsfake"
+)
+
+func TestIDFiltersShouldBatchIDs(t *testing.T) {
+	// given
+	ids := make([]string, 0)
+	for i := 0; i < 2*maxFilterValues+50; i++ {
+		ids = append(ids, fmt.Sprintf("i-%04d", i))
+	}
+
+	// when
+	filters := idFilters("instance-id", ids)
+
+	// then
+	if len(filters) != 3 {
+		t.Fatal("Expected 3 filters, got ", len(filters))
+	}
+	for i, expected := range []int{maxFilterValues, maxFilterValues, 50} {
+		if len(filters[i].Values) != expected {
+			t.Error("Expected ", expected, " values in filter ", i, " got ", len(filters[i].Values))
+		}
+	}
+	if aws.StringValue(filters[2].Values[49]) != ids[len(ids)-1] {
+		t.Error("Expected the last ID in the last filter, got ", aws.StringValue(filters[2].Values[49]))
+	}
+	if len(idFilters("instance-id", nil)) != 0 {
+		t.Error("Expected no filter without IDs")
+	}
+}
+
+func TestRunShouldHandleFailedNatGatewayWithoutSubnet(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	fake.ec2.AddNatGateway(&ec2.NatGateway{
+		NatGatewayId: aws.String("nat-failed"),
+		State:        aws.String(ec2.NatGatewayStateFailed),
+		Tags:         awsfake.Tags(testTagKey, "test"),
+	})
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if report.Failed() {
+		t.Error("Expected no failures, got ", report.Failures)
+	}
+	for _, id := range env.IDs() {
+		if fake.ec2.Exists(id) {
+			t.Error("Expected resource to be removed: ", id)
+		}
+	}
+}
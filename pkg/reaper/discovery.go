@@ -0,0 +1,117 @@
+package reaper
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
+)
+
+// maps resource part of EC2 ARN to resource type used by reaper
+var arnResourceTypes = map[string]string{
+	"instance":         "Instance",
+	"security-group":   "SecurityGroup",
+	"natgateway":       "NatGateway",
+	"elastic-ip":       "EIP",
+	"internet-gateway": "InternetGateway",
+	"route-table":      "RouteTable",
+	"subnet":           "Subnet",
+	"vpc":              "VPC",
+}
+
+// lists resources which belong to resource group following all result pages, returns empty list if resource group does not exist
+func (r *Reaper) listGroupResources() ([]Resource, error) {
+
+	resources := make([]Resource, 0)
+	errResourcesList := r.clients.ResourceGroups.ListGroupResourcesPages(&resourcegroups.ListGroupResourcesInput{
+		GroupName: aws.String(r.config.GroupName),
+	}, func(page *resourcegroups.ListGroupResourcesOutput, lastPage bool) bool {
+		for _, element := range page.ResourceIdentifiers {
+			resources = append(resources, Resource{
+				Type: strings.Split(*element.ResourceType, ":")[4],
+				ID:   strings.Split(*element.ResourceArn, "/")[1],
+				ARN:  *element.ResourceArn,
+			})
+		}
+		return true
+	})
+
+	if errResourcesList != nil {
+		if aerr, ok := errResourcesList.(awserr.Error); ok && aerr.Code() == "NotFoundException" {
+			log.Println("Resource group: ", r.config.GroupName, " not found.")
+			r.groupFound = false
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Resource group: Cannot get list of resources: %w", errResourcesList)
+	}
+
+	r.groupFound = true
+	return resources, nil
+}
+
+// returns Resource Groups Tagging API filters of EC2 resource types handled by reaper, in stable order
+func resourceTypeFilters() []*string {
+	filters := make([]*string, 0, len(arnResourceTypes))
+	for arnType := range arnResourceTypes {
+		filters = append(filters, aws.String("ec2:"+arnType))
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return *filters[i] < *filters[j]
+	})
+	return filters
+}
+
+// lists EC2 resources tagged with given tag using Resource Groups Tagging API following all result pages
+func listTaggedResources(taggingClient resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI, tagKey, tagValue string) ([]Resource, error) {
+
+	resources := make([]Resource, 0)
+	err := taggingClient.GetResourcesPages(&resourcegroupstaggingapi.GetResourcesInput{
+		ResourceTypeFilters: resourceTypeFilters(),
+		TagFilters: []*resourcegroupstaggingapi.TagFilter{
+			{
+				Key:    aws.String(tagKey),
+				Values: []*string{aws.String(tagValue)},
+			},
+		},
+	}, func(page *resourcegroupstaggingapi.GetResourcesOutput, lastPage bool) bool {
+		for _, mapping := range page.ResourceTagMappingList {
+			resource, ok := resourceFromARN(*mapping.ResourceARN)
+			if !ok {
+				log.Println("Tagging: Skipping unsupported resource: ", *mapping.ResourceARN)
+				continue
+			}
+			resources = append(resources, resource)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Tagging: Cannot get list of tagged resources: %w", err)
+	}
+
+	log.Println("Tagging: Found ", len(resources), " tagged resources.")
+	return resources, nil
+}
+
+// creates resource from EC2 ARN, e.g. arn:aws:ec2:eu-central-1:123456789012:subnet/subnet-1234,
+// returns false if ARN is not of supported type
+func resourceFromARN(arn string) (Resource, bool) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[2] != "ec2" {
+		return Resource{}, false
+	}
+	typeAndID := strings.SplitN(parts[5], "/", 2)
+	if len(typeAndID) != 2 {
+		return Resource{}, false
+	}
+	resourceType, ok := arnResourceTypes[typeAndID[0]]
+	if !ok {
+		return Resource{}, false
+	}
+	return Resource{Type: resourceType, ID: typeAndID[1], ARN: arn}, true
+}
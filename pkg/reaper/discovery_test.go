@@ -0,0 +1,47 @@
+package reaper
+
+import (
+	"testing"
+)
+
+func TestResourceFromARNShouldParseSupportedTypes(t *testing.T) {
+	tests := []struct {
+		arn          string
+		expectedType string
+		expectedID   string
+	}{
+		{"arn:aws:ec2:eu-central-1:123456789012:instance/i-0123", "Instance", "i-0123"},
+		{"arn:aws:ec2:eu-central-1:123456789012:security-group/sg-0123", "SecurityGroup", "sg-0123"},
+		{"arn:aws:ec2:eu-central-1:123456789012:natgateway/nat-0123", "NatGateway", "nat-0123"},
+		{"arn:aws:ec2:eu-central-1:123456789012:elastic-ip/eipalloc-0123", "EIP", "eipalloc-0123"},
+		{"arn:aws:ec2:eu-central-1:123456789012:internet-gateway/igw-0123", "InternetGateway", "igw-0123"},
+		{"arn:aws:ec2:eu-central-1:123456789012:route-table/rtb-0123", "RouteTable", "rtb-0123"},
+		{"arn:aws:ec2:eu-central-1:123456789012:subnet/subnet-0123", "Subnet", "subnet-0123"},
+		{"arn:aws:ec2:eu-central-1:123456789012:vpc/vpc-0123", "VPC", "vpc-0123"},
+	}
+	for _, tt := range tests {
+		// when
+		resource, ok := resourceFromARN(tt.arn)
+
+		// then
+		if !ok {
+			t.Error("Expected ARN to be supported: ", tt.arn)
+			continue
+		}
+		if resource.Type != tt.expectedType || resource.ID != tt.expectedID || resource.ARN != tt.arn {
+			t.Error("Expected ", tt.expectedType, "/", tt.expectedID, " from ", tt.arn, " got ", resource)
+		}
+	}
+}
+
+func TestResourceFromARNShouldSkipUnsupportedTypes(t *testing.T) {
+	for _, arn := range []string{
+		"arn:aws:ec2:eu-central-1:123456789012:volume/vol-0123",
+		"arn:aws:resource-groups:eu-central-1:123456789012:group/epiphany-rg",
+		"not-an-arn",
+	} {
+		if resource, ok := resourceFromARN(arn); ok {
+			t.Error("Expected ARN to be skipped: ", arn, " got ", resource)
+		}
+	}
+}
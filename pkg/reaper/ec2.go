@@ -0,0 +1,571 @@
+package reaper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/aws/aws-sdk-go/service/resourcegroups/resourcegroupsiface"
+)
+
+// removes ec2 using ec2 client based on resource that belongs to environment, waits for termination
+// at most timeout, so resources used by instance can be removed right after it
+func removeEc2(ec2Client ec2iface.EC2API, ec2ToRemove Resource, timeout time.Duration) error {
+
+	ec2ToRemoveID := ec2ToRemove.ID
+	log.Println("EC2: Removing instance with ID: ", ec2ToRemoveID)
+
+	ec2DescInp := &ec2.DescribeInstancesInput{
+		InstanceIds: []*string{&ec2ToRemoveID},
+	}
+
+	outDesc, errDesc := ec2Client.DescribeInstances(ec2DescInp)
+	if errDesc != nil {
+		return newError(ec2ToRemove, "describe", 0, errDesc)
+	}
+	log.Printf("EC2: Describe output: %s", outDesc)
+
+	if outDesc.Reservations != nil {
+
+		instancesToTerminateInp := &ec2.TerminateInstancesInput{
+			InstanceIds: []*string{&ec2ToRemoveID},
+		}
+
+		outputTerm, errTerm := ec2Client.TerminateInstances(instancesToTerminateInp)
+		if errTerm != nil {
+			return newError(ec2ToRemove, "terminate", 0, errTerm)
+		}
+		log.Printf("EC2: Terminate output: %s", outputTerm)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		errWait := ec2Client.WaitUntilInstanceTerminatedWithContext(ctx, ec2DescInp,
+			request.WithWaiterDelay(request.ConstantWaiterDelay(terminationPollDelay)),
+			request.WithWaiterMaxAttempts(int(timeout/terminationPollDelay)+1))
+		if errWait != nil {
+			return newError(ec2ToRemove, "wait for termination", 0, errWait)
+		}
+		log.Println("EC2: Instance terminated: ", ec2ToRemoveID)
+	}
+
+	return nil
+}
+
+// removes route table and its subnet associations using ec2 client based on resource that belongs to environment,
+// main route table is excluded from removal by addDependencies, it is removed together with VPC
+func removeRouteTable(ec2Client ec2iface.EC2API, rtToRemove Resource) error {
+
+	rtIDToRemove := rtToRemove.ID
+	log.Println("RouteTable: rtIDToRemove: ", rtIDToRemove)
+
+	descOut, descErr := ec2Client.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{idFilter("route-table-id", []string{rtIDToRemove})},
+	})
+	if descErr != nil {
+		return newError(rtToRemove, "describe", 0, descErr)
+	}
+
+	for _, rt := range descOut.RouteTables {
+		for _, association := range rt.Associations {
+			_, err := ec2Client.DisassociateRouteTable(&ec2.DisassociateRouteTableInput{
+				AssociationId: association.RouteTableAssociationId,
+			})
+			if err != nil {
+				return newError(rtToRemove, "disassociate", 0, err)
+			}
+			log.Println("RouteTable: Disassociated route table from subnet: ", aws.StringValue(association.SubnetId))
+		}
+	}
+
+	rtToDeleteInp := &ec2.DeleteRouteTableInput{
+		RouteTableId: &rtIDToRemove,
+	}
+
+	output, err := ec2Client.DeleteRouteTable(rtToDeleteInp)
+
+	if err != nil {
+		return newError(rtToRemove, "delete", 0, err)
+	}
+
+	log.Println("RouteTable: Deleting route table: ", output)
+
+	return nil
+}
+
+// removes security group using ec2 client based on resource that belongs to environment
+func removeSecurityGroup(ec2Client ec2iface.EC2API, sgToRemove Resource) error {
+
+	sgIDToRemove := sgToRemove.ID
+	log.Println("Security Group: sgIdToRemove: ", sgIDToRemove)
+
+	secGrpInp := &ec2.DeleteSecurityGroupInput{GroupId: &sgIDToRemove}
+
+	output, err := ec2Client.DeleteSecurityGroup(secGrpInp)
+	if err != nil {
+		return newError(sgToRemove, "delete", 0, err)
+	}
+
+	log.Println("Security Group: Deleting security group: ", output)
+
+	return nil
+}
+
+// removes internet gateway using ec2 client based on resource that belongs to environment
+func removeInternetGateway(ec2Client ec2iface.EC2API, igToRemove Resource) error {
+
+	igIDToRemove := igToRemove.ID
+	log.Println("Internet Gateway: igIdToRemove: ", igIDToRemove)
+
+	igDescribeInp := &ec2.DescribeInternetGatewaysInput{
+		InternetGatewayIds: []*string{&igIDToRemove},
+	}
+
+	descOut, descErr := ec2Client.DescribeInternetGateways(igDescribeInp)
+
+	if descErr != nil {
+		return newError(igToRemove, "describe", 0, descErr)
+	}
+	log.Println("Internet Gateway: Describing internet gateway: ", descOut)
+
+	if len(descOut.InternetGateways) == 0 {
+		log.Println("Internet Gateway: Internet gateway already removed: ", igIDToRemove)
+		return nil
+	}
+
+	for _, attachment := range descOut.InternetGateways[0].Attachments {
+		igDetachInp := &ec2.DetachInternetGatewayInput{
+			InternetGatewayId: &igIDToRemove,
+			VpcId:             attachment.VpcId,
+		}
+
+		detachOut, detachErr := ec2Client.DetachInternetGateway(igDetachInp)
+		if detachErr != nil {
+			return newError(igToRemove, "detach", 0, detachErr)
+		}
+		log.Println("Internet Gateway: Detaching internet gateway: ", detachOut)
+	}
+
+	igDeleteInp := &ec2.DeleteInternetGatewayInput{
+		InternetGatewayId: &igIDToRemove,
+	}
+
+	delOut, delErr := ec2Client.DeleteInternetGateway(igDeleteInp)
+	if delErr != nil {
+		return newError(igToRemove, "delete", 0, delErr)
+	}
+	log.Println("Internet Gateway: Deleting internet gateway: ", delOut)
+
+	return nil
+}
+
+// remove single nat gateway using ec2 client, waits for deletion at most timeout, returned error holds number of
+// retries performed
+func removeSingleNatGatewayWithRetries(ec2Client ec2iface.EC2API, ngToRemove Resource, timeout time.Duration) error {
+
+	found := true
+
+	for retry := 0; retry <= retries && found; retry++ {
+		if retry > 0 {
+			time.Sleep(backoff(retry - 1))
+		}
+
+		var err error
+		found, err = describeNatGateway(ec2Client, ngToRemove)
+		if err != nil {
+			return withRetries(err, retry)
+		}
+
+		if found == false {
+			continue
+		}
+
+		found, err = removeNatGateway(ec2Client, ngToRemove)
+		if err != nil {
+			return withRetries(err, retry)
+		}
+
+		if found == false {
+			continue
+		}
+
+		if err := waitForNatGatewayDelete(ec2Client, ngToRemove, timeout); err != nil {
+			return withRetries(err, retry)
+		}
+		found = false
+
+		log.Println("Nat Gateway: Deleted NAT Gateway. ", ngToRemove.ID, " Retry: ", retry)
+	}
+
+	if found {
+		return newError(ngToRemove, "delete", retries, errStillExists)
+	}
+	return nil
+}
+
+// describe Nat Gateway based on ec2 client and Nat Gateway resource, returns false if Nat Gateway not found or deleted
+func describeNatGateway(ec2Client ec2iface.EC2API, ngToDescribe Resource) (bool, error) {
+	descInp := &ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: []*string{aws.String(ngToDescribe.ID)},
+	}
+
+	outDesc, errDesc := ec2Client.DescribeNatGateways(descInp)
+	if errDesc != nil {
+		log.Println(errDesc)
+		if aerr, ok := errDesc.(awserr.Error); ok && aerr.Code() == "NatGatewayNotFound" {
+			log.Println("Nat Gateway: Nat Gateway not found.")
+			return false, nil
+		}
+		return false, newError(ngToDescribe, "describe", 0, errDesc)
+	}
+	log.Printf("Nat Gateway: Describe output: %s", outDesc)
+
+	if len(outDesc.NatGateways) == 0 || aws.StringValue(outDesc.NatGateways[0].State) == ec2.NatGatewayStateDeleted {
+		log.Print("Nat Gateway: Element not found or has been already deleted.")
+		return false, nil
+	}
+	return true, nil
+}
+
+// appropriate Nat Gateway delete method based on ec2 client and Nat Gateway resource, returns false if Nat Gateway not found
+func removeNatGateway(ec2Client ec2iface.EC2API, ngToRemove Resource) (bool, error) {
+	ngDelInp := &ec2.DeleteNatGatewayInput{
+		NatGatewayId: aws.String(ngToRemove.ID),
+	}
+
+	_, err := ec2Client.DeleteNatGateway(ngDelInp)
+
+	if err != nil {
+		log.Println("Nat Gateway: Error: ", err)
+		if aerr, ok := err.(awserr.Error); ok {
+			if aerr.Code() == "NatGatewayNotFound" {
+				log.Print("Nat Gateway: Element not found.", err)
+				return false, nil
+			}
+			if aerr.Code() != "ResourceNotReady" {
+				return false, newError(ngToRemove, "delete", 0, err)
+			}
+		} else {
+			return false, newError(ngToRemove, "delete", 0, err)
+		}
+
+	}
+	return true, nil
+}
+
+// wait at most timeout for Nat Gateway to reach deleted state, SDK has no waiter for it, so describe is polled
+// like instance waiter does
+func waitForNatGatewayDelete(ec2Client ec2iface.EC2API, ngToWait Resource, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for {
+		found, err := describeNatGateway(ec2Client, ngToWait)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return newError(ngToWait, "wait for deletion", 0, ctx.Err())
+		case <-time.After(terminationPollDelay):
+		}
+	}
+}
+
+// removes subnet using ec2 client based on resource that belongs to environment
+func removeSubnet(ec2Client ec2iface.EC2API, subnetToRemove Resource) error {
+
+	subnetIDToRemove := subnetToRemove.ID
+	log.Println("Subnet: subnetIdToRemove: ", subnetIDToRemove)
+
+	subnetInp := &ec2.DeleteSubnetInput{
+		SubnetId: &subnetIDToRemove,
+	}
+
+	output, err := ec2Client.DeleteSubnet(subnetInp)
+	if err != nil {
+		return newError(subnetToRemove, "delete", 0, err)
+	}
+	log.Println("Subnet: Deleting subnet: ", output)
+
+	return nil
+}
+
+// removes vpc using ec2 client based on resource that belongs to environment
+func removeVpc(ec2Client ec2iface.EC2API, vpcToRemove Resource) error {
+
+	vpcIDToRemove := vpcToRemove.ID
+	log.Println("VPC: vpcIdToRemove: ", vpcIDToRemove)
+
+	vpcToDeleteInp := &ec2.DeleteVpcInput{
+		VpcId: &vpcIDToRemove,
+	}
+
+	output, err := ec2Client.DeleteVpc(vpcToDeleteInp)
+	if err != nil {
+		return newError(vpcToRemove, "delete", 0, err)
+	}
+	log.Println("VPC: Delete VPC: ", output)
+
+	return nil
+}
+
+// removes key pair using ec2 client based on resource that belongs to environment
+func removeKeyPair(ec2Client ec2iface.EC2API, kpToRemove Resource) error {
+
+	log.Println("Key Pair: kpNameToRemove: ", kpToRemove.ID)
+
+	removeKeyInp := &ec2.DeleteKeyPairInput{
+		KeyName: aws.String(kpToRemove.ID),
+	}
+
+	output, err := ec2Client.DeleteKeyPair(removeKeyInp)
+	if err != nil {
+		return newError(kpToRemove, "delete", 0, err)
+	}
+	log.Println("Key Pair: Deleting key pair: ", output)
+	return nil
+}
+
+// describes key pairs using ec2 client based on resource tag or name prefix, key pairs are created
+// with key_name_prefix so their names have random suffix. DescribeKeyPairs is not paginated and returns all results
+func describeKeyPairs(ec2Client ec2iface.EC2API, tagKey, tagValue, namePrefix string) ([]Resource, error) {
+
+	filters := []*ec2.Filter{
+		{
+			Name:   aws.String("tag:" + tagKey),
+			Values: []*string{aws.String(tagValue)},
+		},
+		{
+			Name:   aws.String("key-name"),
+			Values: []*string{aws.String(namePrefix + "*")},
+		},
+	}
+
+	keyPairs := make([]Resource, 0)
+	// filters are combined with AND, so key pairs are described separately by each of them
+	for _, filter := range filters {
+		describeKps, err := ec2Client.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+			Filters: []*ec2.Filter{filter},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Key Pair: Cannot get key pair list: %w", err)
+		}
+
+		for _, kp := range describeKps.KeyPairs {
+			keyPairs = appendMissing(keyPairs, Resource{Type: "KeyPair", ID: *kp.KeyName})
+		}
+	}
+	return keyPairs, nil
+}
+
+// describes elastic IPs using ec2 client based on resource tag, DescribeAddresses is not paginated and returns all results
+func describeAddresses(ec2Client ec2iface.EC2API, tagKey, tagValue string) ([]Resource, error) {
+
+	eipDescInp := &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name: aws.String("tag:" + tagKey),
+				Values: []*string{
+					aws.String(tagValue),
+				},
+			},
+		},
+	}
+
+	describeEips, err := ec2Client.DescribeAddresses(eipDescInp)
+	if err != nil {
+		return nil, fmt.Errorf("EIP: Cannot get EIP list: %w", err)
+	}
+
+	addresses := make([]Resource, 0, len(describeEips.Addresses))
+	for _, eip := range describeEips.Addresses {
+		// only VPC addresses have allocation ID and module creates only those
+		if id := aws.StringValue(eip.AllocationId); id != "" {
+			addresses = append(addresses, Resource{Type: "EIP", ID: id})
+		}
+	}
+	return addresses, nil
+}
+
+// lists detached network interfaces tagged with given tag or placed in one of subnets and network interfaces which stay
+// after termination of one of instances (they get detached only when instance is removed), describe is followed for
+// all result pages
+func describeNetworkInterfaces(ec2Client ec2iface.EC2API, tagKey, tagValue string, subnetIDs, instanceIDs []string) ([]Resource, error) {
+
+	filters := [][]*ec2.Filter{{
+		{Name: aws.String("tag:" + tagKey), Values: []*string{aws.String(tagValue)}},
+		{Name: aws.String("status"), Values: []*string{aws.String(ec2.NetworkInterfaceStatusAvailable)}},
+	}}
+	for _, subnetFilter := range idFilters("subnet-id", subnetIDs) {
+		filters = append(filters, []*ec2.Filter{
+			subnetFilter,
+			{Name: aws.String("status"), Values: []*string{aws.String(ec2.NetworkInterfaceStatusAvailable)}},
+		})
+	}
+	for _, instanceFilter := range idFilters("attachment.instance-id", instanceIDs) {
+		filters = append(filters, []*ec2.Filter{
+			instanceFilter,
+			{Name: aws.String("attachment.delete-on-termination"), Values: []*string{aws.String("false")}},
+		})
+	}
+
+	resources := make([]Resource, 0)
+	for _, filter := range filters {
+		err := ec2Client.DescribeNetworkInterfacesPages(&ec2.DescribeNetworkInterfacesInput{
+			Filters: filter,
+		}, func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+			for _, eni := range page.NetworkInterfaces {
+				resources = appendMissing(resources, Resource{Type: "NetworkInterface", ID: *eni.NetworkInterfaceId})
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Network Interface: Cannot get list of network interfaces: %w", err)
+		}
+	}
+
+	log.Println("Network Interface: Found ", len(resources), " detached or retained network interfaces.")
+	return resources, nil
+}
+
+// removes detached network interface using ec2 client
+func removeNetworkInterface(ec2Client ec2iface.EC2API, eniToRemove Resource) error {
+
+	log.Println("Network Interface: Removing network interface: ", eniToRemove.ID)
+
+	_, err := ec2Client.DeleteNetworkInterface(&ec2.DeleteNetworkInterfaceInput{
+		NetworkInterfaceId: aws.String(eniToRemove.ID),
+	})
+	if err != nil {
+		return newError(eniToRemove, "delete", 0, err)
+	}
+	return nil
+}
+
+// lists volumes tagged with given tag which are unattached or stay after termination of one of instances, describe
+// is followed for all result pages
+func describeVolumes(ec2Client ec2iface.EC2API, tagKey, tagValue string, instanceIDs []string) ([]Resource, error) {
+
+	tagFilter := &ec2.Filter{Name: aws.String("tag:" + tagKey), Values: []*string{aws.String(tagValue)}}
+	filters := [][]*ec2.Filter{{
+		tagFilter,
+		{Name: aws.String("status"), Values: []*string{aws.String(ec2.VolumeStateAvailable)}},
+	}}
+	for _, instanceFilter := range idFilters("attachment.instance-id", instanceIDs) {
+		filters = append(filters, []*ec2.Filter{
+			tagFilter,
+			instanceFilter,
+			{Name: aws.String("attachment.delete-on-termination"), Values: []*string{aws.String("false")}},
+		})
+	}
+
+	resources := make([]Resource, 0)
+	for _, filter := range filters {
+		err := ec2Client.DescribeVolumesPages(&ec2.DescribeVolumesInput{
+			Filters: filter,
+		}, func(page *ec2.DescribeVolumesOutput, lastPage bool) bool {
+			for _, volume := range page.Volumes {
+				resources = appendMissing(resources, Resource{Type: "Volume", ID: *volume.VolumeId})
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Volume: Cannot get list of volumes: %w", err)
+		}
+	}
+
+	log.Println("Volume: Found ", len(resources), " unattached or retained volumes.")
+	return resources, nil
+}
+
+// removes unattached volume using ec2 client
+func removeVolume(ec2Client ec2iface.EC2API, volumeToRemove Resource) error {
+
+	log.Println("Volume: Removing volume: ", volumeToRemove.ID)
+
+	_, err := ec2Client.DeleteVolume(&ec2.DeleteVolumeInput{
+		VolumeId: aws.String(volumeToRemove.ID),
+	})
+	if err != nil {
+		return newError(volumeToRemove, "delete", 0, err)
+	}
+	return nil
+}
+
+// releases elastic IP using ec2 client based on allocation ID
+func releaseAddress(ec2Client ec2iface.EC2API, eip Resource) error {
+
+	log.Printf("EIP: Releasing EIP with AllocationId: %s", eip.ID)
+
+	eipToReleaseInp := &ec2.ReleaseAddressInput{
+		AllocationId: aws.String(eip.ID),
+	}
+
+	found := true
+	for retry := 0; retry <= retries && found; retry++ {
+		if retry > 0 {
+			time.Sleep(backoff(retry - 1))
+		}
+		_, err := ec2Client.ReleaseAddress(eipToReleaseInp)
+		if err == nil {
+			log.Println("EIP: Released EIP: ", eip.ID)
+			found = false
+			continue
+		}
+		if aerr, ok := err.(awserr.Error); ok {
+			if aerr.Code() == "InvalidAllocationID.NotFound" {
+				log.Print("EIP: Element not found.", err)
+				found = false
+				continue
+			}
+			if aerr.Code() != "AuthFailure" {
+				return newError(eip, "release", retry, err)
+			}
+		} else {
+			return newError(eip, "release", retry, err)
+		}
+		log.Println("EIP: Releasing EIP. Retry: ", retry)
+	}
+
+	if found {
+		return newError(eip, "release", retries, errStillExists)
+	}
+	return nil
+}
+
+// removes resource group using resource groups client based on name
+func removeResourceGroup(rgClient resourcegroupsiface.ResourceGroupsAPI, rgToRemoveName string) error {
+
+	log.Println("Resource Group: Removing resource group: ", rgToRemoveName)
+	rgDelInp := resourcegroups.DeleteGroupInput{
+		GroupName: aws.String(rgToRemoveName),
+	}
+	rgDelOut, rgDelErr := rgClient.DeleteGroup(&rgDelInp)
+	if rgDelErr != nil {
+		if aerr, ok := rgDelErr.(awserr.Error); ok && aerr.Code() == "NotFoundException" {
+			log.Println("Resource Group: Resource group not found. ")
+			return nil
+		}
+		return newError(Resource{Type: "ResourceGroup", ID: rgToRemoveName}, "delete", 0, rgDelErr)
+	}
+
+	log.Println("Resource Group: Deleting resource group: ", rgDelOut)
+	return nil
+}
+
+// sets number of retries performed on reaper error
+func withRetries(err error, retries int) error {
+	if e, ok := err.(*Error); ok {
+		e.Retries = retries
+	}
+	return err
+}
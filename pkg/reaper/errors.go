@@ -0,0 +1,51 @@
+package reaper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// errStillExists is reported when resource was not removed within all retries
+var errStillExists = errors.New("resource still exists after all retries")
+
+// errBlocked is reported for resources which were not removed because removal of resource which uses them failed
+var errBlocked = errors.New("not removed because a dependent resource could not be removed")
+
+// Error describes single action performed by reaper which failed
+type Error struct {
+	// Resource on which the action failed
+	Resource Resource
+	// Action which failed, e.g. terminate, detach, delete
+	Action string
+	// Code is AWS error code, empty when error did not come from AWS
+	Code string
+	// Retries is the number of retries performed before giving up
+	Retries int
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Action, e.Resource, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// creates Error for action on resource, AWS error code is extracted from err
+func newError(resource Resource, action string, retries int, err error) *Error {
+	code := ""
+	if aerr, ok := err.(awserr.Error); ok {
+		code = aerr.Code()
+	}
+	return &Error{Resource: resource, Action: action, Code: code, Retries: retries, Err: err}
+}
+
+// isNotFound returns true if err is AWS error reporting that resource does not exist, e.g. InvalidSubnetID.NotFound
+func isNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && strings.HasSuffix(e.Code, ".NotFound")
+}
@@ -0,0 +1,25 @@
+package reaper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestNewErrorShouldExtractAWSErrorCode(t *testing.T) {
+	// given
+	resource := Resource{Type: "Subnet", ID: "subnet-1", ARN: "arn:aws:ec2:eu-central-1:123:subnet/subnet-1"}
+	awsErr := awserr.New("DependencyViolation", "The subnet has dependencies", nil)
+
+	// when
+	err := newError(resource, "delete", 2, awsErr)
+
+	// then
+	if err.Code != "DependencyViolation" {
+		t.Error("Expected code DependencyViolation, got ", err.Code)
+	}
+	if !errors.Is(err, awsErr) {
+		t.Error("Expected error to wrap AWS error")
+	}
+}
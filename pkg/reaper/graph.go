@@ -0,0 +1,196 @@
+package reaper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// graph holds discovered resources and dependencies between them.
+// Edge from A to B means that A uses B, so A has to be removed before B.
+type graph struct {
+	nodes map[string]*node
+	// resources excluded from removal as they are removed together with other resources
+	excluded []Resource
+}
+
+type node struct {
+	resource Resource
+	// nodes which have to be removed before this one
+	usedBy []*node
+	// nodes which can be removed only after this one
+	uses []*node
+}
+
+func newGraph(resources []Resource) *graph {
+	g := &graph{nodes: make(map[string]*node, len(resources))}
+	for _, resource := range resources {
+		g.nodes[resource.ID] = &node{resource: resource}
+	}
+	return g
+}
+
+// addDependency marks that resource with ID user uses resource with ID used, edges to unknown resources are ignored
+func (g *graph) addDependency(user, used string) {
+	from, okFrom := g.nodes[user]
+	to, okTo := g.nodes[used]
+	if !okFrom || !okTo || from == to {
+		return
+	}
+	for _, existing := range from.uses {
+		if existing == to {
+			return
+		}
+	}
+	from.uses = append(from.uses, to)
+	to.usedBy = append(to.usedBy, from)
+}
+
+// exclude removes resource with ID from graph together with its dependencies, unknown resources are ignored
+func (g *graph) exclude(id string) {
+	n, ok := g.nodes[id]
+	if !ok {
+		return
+	}
+	for _, used := range n.uses {
+		used.usedBy = withoutNode(used.usedBy, n)
+	}
+	for _, user := range n.usedBy {
+		user.uses = withoutNode(user.uses, n)
+	}
+	delete(g.nodes, id)
+	g.excluded = append(g.excluded, n.resource)
+}
+
+func withoutNode(nodes []*node, n *node) []*node {
+	result := make([]*node, 0, len(nodes))
+	for _, existing := range nodes {
+		if existing != n {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
+
+// order returns resources grouped in steps, resources from one step do not depend on each other and
+// can be removed in parallel once all previous steps are completed
+func (g *graph) order() ([][]Resource, error) {
+	pending := make(map[*node]int, len(g.nodes))
+	current := make([]*node, 0)
+	for _, n := range g.nodes {
+		pending[n] = len(n.usedBy)
+		if len(n.usedBy) == 0 {
+			current = append(current, n)
+		}
+	}
+
+	steps := make([][]Resource, 0)
+	visited := 0
+	for len(current) > 0 {
+		sortNodes(current)
+		step := make([]Resource, 0, len(current))
+		next := make([]*node, 0)
+		for _, n := range current {
+			step = append(step, n.resource)
+			visited++
+			for _, used := range n.uses {
+				pending[used]--
+				if pending[used] == 0 {
+					next = append(next, used)
+				}
+			}
+		}
+		steps = append(steps, step)
+		current = next
+	}
+
+	if visited != len(g.nodes) {
+		cycle := make([]string, 0)
+		for n, count := range pending {
+			if count > 0 {
+				cycle = append(cycle, n.resource.String())
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("dependency cycle between resources: %s", strings.Join(cycle, ", "))
+	}
+	return steps, nil
+}
+
+// walk calls remove for every resource as soon as all resources which use it are removed, independent
+// branches are processed in parallel by at most workers at a time. Returns errors keyed by resource ID, resources
+// depending on failed ones are not removed and reported with errBlocked. Graph has to be acyclic, see order.
+func (g *graph) walk(workers int, remove func(Resource) error) map[string]error {
+	done := make(map[*node]chan struct{}, len(g.nodes))
+	for _, n := range g.nodes {
+		done[n] = make(chan struct{})
+	}
+
+	var mutex sync.Mutex
+	errs := make(map[string]error)
+	failed := func(n *node) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		_, ok := errs[n.resource.ID]
+		return ok
+	}
+
+	// limits number of resources removed at the same time
+	pool := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for _, n := range g.nodes {
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+			defer close(done[n])
+
+			blocked := false
+			for _, user := range n.usedBy {
+				<-done[user]
+				if failed(user) {
+					blocked = true
+				}
+			}
+
+			var err error
+			if blocked {
+				err = errBlocked
+			} else {
+				pool <- struct{}{}
+				err = remove(n.resource)
+				<-pool
+			}
+
+			if err != nil {
+				mutex.Lock()
+				errs[n.resource.ID] = err
+				mutex.Unlock()
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// sorts nodes by removal rank of resource type and then by ID to keep output stable
+func sortNodes(nodes []*node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		ri, rj := typeRank(nodes[i].resource.Type), typeRank(nodes[j].resource.Type)
+		if ri != rj {
+			return ri < rj
+		}
+		return nodes[i].resource.ID < nodes[j].resource.ID
+	})
+}
+
+func typeRank(resourceType string) int {
+	for i, t := range resourcesTypes {
+		if t == resourceType {
+			return i
+		}
+	}
+	return len(resourcesTypes)
+}
@@ -0,0 +1,214 @@
+package reaper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// creates graph of environment with one instance, nat gateway in public subnet and route table of private subnet
+func testGraph() *graph {
+	g := newGraph([]Resource{
+		{Type: "VPC", ID: "vpc-1"},
+		{Type: "Subnet", ID: "subnet-public"},
+		{Type: "Subnet", ID: "subnet-private"},
+		{Type: "SecurityGroup", ID: "sg-1"},
+		{Type: "Instance", ID: "i-1"},
+		{Type: "InternetGateway", ID: "igw-1"},
+		{Type: "NatGateway", ID: "nat-1"},
+		{Type: "EIP", ID: "eipalloc-1"},
+		{Type: "RouteTable", ID: "rtb-private"},
+	})
+	g.addDependency("i-1", "sg-1")
+	g.addDependency("i-1", "subnet-private")
+	g.addDependency("i-1", "igw-1")
+	g.addDependency("nat-1", "eipalloc-1")
+	g.addDependency("nat-1", "subnet-public")
+	g.addDependency("nat-1", "igw-1")
+	g.addDependency("igw-1", "vpc-1")
+	g.addDependency("rtb-private", "subnet-private")
+	g.addDependency("rtb-private", "vpc-1")
+	g.addDependency("subnet-public", "vpc-1")
+	g.addDependency("subnet-private", "vpc-1")
+	g.addDependency("sg-1", "vpc-1")
+	return g
+}
+
+func ids(step []Resource) []string {
+	result := make([]string, 0, len(step))
+	for _, resource := range step {
+		result = append(result, resource.ID)
+	}
+	return result
+}
+
+func TestOrderShouldRemoveUsersBeforeUsedResources(t *testing.T) {
+	// given
+	g := testGraph()
+
+	// when
+	steps, err := g.order()
+	if err != nil {
+		t.Fatal("There was an error ordering graph: ", err)
+	}
+
+	// then
+	expected := [][]string{
+		{"i-1", "nat-1", "rtb-private"},
+		{"sg-1", "eipalloc-1", "igw-1", "subnet-private", "subnet-public"},
+		{"vpc-1"},
+	}
+	if len(steps) != len(expected) {
+		t.Fatal("Expected ", len(expected), " steps, got ", len(steps), ": ", steps)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(ids(steps[i]), expected[i]) {
+			t.Error("Expected step ", i+1, " to be ", expected[i], " but got ", ids(steps[i]))
+		}
+	}
+}
+
+func TestOrderShouldIgnoreDependenciesOnUnknownResources(t *testing.T) {
+	// given
+	g := newGraph([]Resource{{Type: "Subnet", ID: "subnet-1"}})
+	g.addDependency("subnet-1", "vpc-removed")
+
+	// when
+	steps, err := g.order()
+
+	// then
+	if err != nil {
+		t.Fatal("There was an error ordering graph: ", err)
+	}
+	if len(steps) != 1 || steps[0][0].ID != "subnet-1" {
+		t.Error("Expected single step with subnet-1, got ", steps)
+	}
+}
+
+func TestOrderShouldLeaveOutExcludedResources(t *testing.T) {
+	// given
+	g := testGraph()
+
+	// when
+	g.exclude("rtb-private")
+	steps, err := g.order()
+
+	// then
+	if err != nil {
+		t.Fatal("There was an error ordering graph: ", err)
+	}
+	if !reflect.DeepEqual(ids(steps[0]), []string{"i-1", "nat-1"}) {
+		t.Error("Expected first step without excluded route table, got ", ids(steps[0]))
+	}
+	if len(g.excluded) != 1 || g.excluded[0].ID != "rtb-private" {
+		t.Error("Expected route table to be excluded, got ", g.excluded)
+	}
+}
+
+func TestOrderShouldFailOnCycle(t *testing.T) {
+	// given
+	g := newGraph([]Resource{{Type: "Subnet", ID: "subnet-1"}, {Type: "VPC", ID: "vpc-1"}})
+	g.addDependency("subnet-1", "vpc-1")
+	g.addDependency("vpc-1", "subnet-1")
+
+	// when
+	_, err := g.order()
+
+	// then
+	if err == nil {
+		t.Error("Expected error for dependency cycle")
+	}
+}
+
+func TestWalkShouldRemoveInDependencyOrder(t *testing.T) {
+	// given
+	g := testGraph()
+	var mutex sync.Mutex
+	removed := make(map[string]bool)
+
+	// when
+	errs := g.walk(2, func(resource Resource) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for _, user := range g.nodes[resource.ID].usedBy {
+			if !removed[user.resource.ID] {
+				t.Error("Resource ", resource.ID, " removed before ", user.resource.ID)
+			}
+		}
+		removed[resource.ID] = true
+		return nil
+	})
+
+	// then
+	if len(errs) != 0 {
+		t.Error("Expected no errors, got ", errs)
+	}
+	if len(removed) != len(g.nodes) {
+		t.Error("Expected ", len(g.nodes), " removed resources, got ", len(removed))
+	}
+}
+
+func TestWalkShouldBlockResourcesUsedByFailedOnes(t *testing.T) {
+	// given
+	g := testGraph()
+	failure := errors.New("DependencyViolation")
+
+	// when
+	errs := g.walk(2, func(resource Resource) error {
+		if resource.ID == "nat-1" {
+			return failure
+		}
+		return nil
+	})
+
+	// then
+	if errs["nat-1"] != failure {
+		t.Error("Expected nat-1 to fail with ", failure, " got ", errs["nat-1"])
+	}
+	for _, id := range []string{"eipalloc-1", "subnet-public", "igw-1", "vpc-1"} {
+		if errs[id] != errBlocked {
+			t.Error("Expected ", id, " to be blocked, got ", errs[id])
+		}
+	}
+	for _, id := range []string{"i-1", "sg-1", "rtb-private", "subnet-private"} {
+		if errs[id] != nil {
+			t.Error("Expected ", id, " to be removed, got ", errs[id])
+		}
+	}
+}
+
+func TestWalkShouldNotRemoveMoreResourcesAtOnceThanWorkers(t *testing.T) {
+	// given
+	subnets := make([]Resource, 0)
+	for i := 0; i < 10; i++ {
+		subnets = append(subnets, Resource{Type: "Subnet", ID: fmt.Sprint("subnet-", i)})
+	}
+	g := newGraph(subnets)
+	var running, maxRunning int32
+
+	// when
+	errs := g.walk(3, func(resource Resource) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			observed := atomic.LoadInt32(&maxRunning)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+
+	// then
+	if len(errs) != 0 {
+		t.Error("Expected no errors, got ", errs)
+	}
+	if maxRunning > 3 {
+		t.Error("Expected at most 3 resources removed at once, got ", maxRunning)
+	}
+}
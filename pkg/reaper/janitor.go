@@ -0,0 +1,433 @@
+package reaper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+)
+
+const (
+	// suffix of names of resource groups created by module
+	groupSuffix = "-rg"
+	// DefaultCreatedTagKey is the key of tag holding creation time of environment
+	DefaultCreatedTagKey = "created_at"
+)
+
+// actions taken by janitor on environment
+const (
+	ActionKeep       = "keep"
+	ActionAllowed    = "allowed"
+	ActionUnknownAge = "unknown age"
+	ActionRemove     = "remove"
+)
+
+// returns current time, variable so tests can move the clock
+var now = time.Now
+
+// JanitorConfig describes which environments janitor sweeps
+type JanitorConfig struct {
+	// TagKey is the key of tag which marks resources of environments, value of the tag is the environment name
+	TagKey string
+	// CreatedTagKey is the key of tag holding creation time of environment (RFC 3339), defaults to DefaultCreatedTagKey.
+	// Module sets the tag on its resources at the first apply. When no resource has the tag (environments created
+	// by older module versions), age is taken from launch time of the oldest instance, which is reset by stop and
+	// start of the instance, and environment without instances has unknown age.
+	CreatedTagKey string
+	// TTL is the age after which environment is removed
+	TTL time.Duration
+	// AllowList holds names of environments which are never removed, path.Match patterns are accepted
+	AllowList []string
+	Region    string
+	// DryRun makes Sweep only log resources that would be deleted
+	DryRun bool
+	// Workers, RequestsPerSecond and TerminationTimeout are passed to reaper of every environment, see Config
+	Workers            int
+	RequestsPerSecond  float64
+	TerminationTimeout time.Duration
+}
+
+// Environment is a single environment found by janitor
+type Environment struct {
+	Name string
+	// GroupName is the name of resource group of environment
+	GroupName string
+	// Created is the creation time of environment, zero when it cannot be determined
+	Created time.Time
+}
+
+// Age returns age of environment at given time, zero when creation time is unknown
+func (e Environment) Age(at time.Time) time.Duration {
+	if e.Created.IsZero() {
+		return 0
+	}
+	return at.Sub(e.Created)
+}
+
+// Sweep is the result of janitor run for a single environment
+type Sweep struct {
+	Environment
+	Action string
+	// Report of reaper, set only when environment was removed
+	Report *Report
+	// Err is set when reaper could not run
+	Err error
+}
+
+// Failed returns true if environment should have been removed but it was not removed completely
+func (s Sweep) Failed() bool {
+	return s.Err != nil || (s.Report != nil && s.Report.Failed())
+}
+
+// JanitorReport summarizes results of Sweep
+type JanitorReport struct {
+	Sweeps []Sweep
+}
+
+// Failed returns true if any environment could not be removed
+func (r *JanitorReport) Failed() bool {
+	for _, sweep := range r.Sweeps {
+		if sweep.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes report in tabular form, followed by reports of removed environments
+func (r *JanitorReport) Print(w io.Writer) {
+	at := now()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENVIRONMENT\tGROUP\tCREATED\tAGE\tACTION\tRESULT")
+	for _, sweep := range r.Sweeps {
+		group, created, age, result := "-", "-", "-", "-"
+		if sweep.GroupName != "" {
+			group = sweep.GroupName
+		}
+		if !sweep.Created.IsZero() {
+			created = sweep.Created.UTC().Format(time.RFC3339)
+			age = sweep.Age(at).Truncate(time.Minute).String()
+		}
+		switch {
+		case sweep.Err != nil:
+			result = sweep.Err.Error()
+		case sweep.Report != nil && sweep.Report.Failed():
+			result = fmt.Sprintf("removed: %d, failed: %d", len(sweep.Report.Removed), len(sweep.Report.Failures))
+		case sweep.Report != nil:
+			result = fmt.Sprintf("removed: %d", len(sweep.Report.Removed))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", sweep.Name, group, created, age, sweep.Action, result)
+	}
+	tw.Flush()
+
+	for _, sweep := range r.Sweeps {
+		if sweep.Report != nil {
+			fmt.Fprintf(w, "\nEnvironment %s:\n", sweep.Name)
+			sweep.Report.Print(w)
+		}
+	}
+}
+
+// Janitor finds environments created by module and removes the ones older than TTL
+type Janitor struct {
+	config JanitorConfig
+	// clients are shared by janitor and reapers of environments, which run one after another
+	clients Clients
+}
+
+// NewJanitor validates config and creates janitor with AWS clients for the configured region, requests of
+// clients are rate limited like requests of reaper
+func NewJanitor(config JanitorConfig) (*Janitor, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	workers := config.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	newSession, err := limitedSession(aws.NewConfig().WithRegion(config.Region), newLimiter(config.RequestsPerSecond, workers))
+	if err != nil {
+		return nil, err
+	}
+	return NewJanitorWithClients(config, sessionClients(newSession))
+}
+
+// NewJanitorWithClients validates config and creates janitor using provided AWS clients
+func NewJanitorWithClients(config JanitorConfig, clients Clients) (*Janitor, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if clients.EC2 == nil || clients.ResourceGroups == nil || clients.Tagging == nil {
+		return nil, fmt.Errorf("all AWS clients are required")
+	}
+
+	return &Janitor{config: config, clients: clients}, nil
+}
+
+// validates config and sets default values
+func (c *JanitorConfig) validate() error {
+	if c.TagKey == "" {
+		return fmt.Errorf("tag key is required")
+	}
+	if c.TTL <= 0 {
+		return fmt.Errorf("TTL has to be positive")
+	}
+	if c.Region == "" {
+		return fmt.Errorf("region is required")
+	}
+	for _, pattern := range c.AllowList {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid allow-list pattern %q: %w", pattern, err)
+		}
+	}
+	if c.CreatedTagKey == "" {
+		c.CreatedTagKey = DefaultCreatedTagKey
+	}
+	if c.RequestsPerSecond <= 0 {
+		c.RequestsPerSecond = DefaultRequestsPerSecond
+	}
+	return nil
+}
+
+// checks if environment is on the allow-list
+func (c *JanitorConfig) allowed(name string) bool {
+	for _, pattern := range c.AllowList {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Environments lists environments created by module, which are resource groups named *-rg selecting resources
+// by the tag key. Tagged EC2 resources, key pairs and instances only tell age of those environments, resources
+// tagged by other tooling with the same tag key do not make environments.
+func (j *Janitor) Environments() ([]Environment, error) {
+	groups, err := j.listGroups()
+	if err != nil {
+		return nil, err
+	}
+	environments := make(map[string]*Environment)
+	for name, groupName := range groups {
+		environments[name] = &Environment{Name: name, GroupName: groupName}
+	}
+	// resources of unknown environments are skipped
+	environment := func(name string) *Environment {
+		if env, ok := environments[name]; ok {
+			return env
+		}
+		return &Environment{}
+	}
+
+	err = j.clients.Tagging.GetResourcesPages(&resourcegroupstaggingapi.GetResourcesInput{
+		ResourceTypeFilters: resourceTypeFilters(),
+		TagFilters:          []*resourcegroupstaggingapi.TagFilter{{Key: aws.String(j.config.TagKey)}},
+	}, func(page *resourcegroupstaggingapi.GetResourcesOutput, lastPage bool) bool {
+		for _, mapping := range page.ResourceTagMappingList {
+			tags := make(map[string]string)
+			for _, tag := range mapping.Tags {
+				tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+			env := environment(tags[j.config.TagKey])
+			env.Created = earliest(env.Created, j.createdFromTag(aws.StringValue(mapping.ResourceARN), tags))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Tagging: Cannot get list of tagged resources: %w", err)
+	}
+
+	describeKps, err := j.clients.EC2.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		Filters: []*ec2.Filter{{Name: aws.String("tag-key"), Values: []*string{aws.String(j.config.TagKey)}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KeyPair: Cannot get list of key pairs: %w", err)
+	}
+	for _, kp := range describeKps.KeyPairs {
+		tags := ec2Tags(kp.Tags)
+		env := environment(tags[j.config.TagKey])
+		env.Created = earliest(env.Created, j.createdFromTag(aws.StringValue(kp.KeyName), tags))
+	}
+
+	// launch time of instances is used only for environments without creation tag
+	launched := make(map[string]time.Time)
+	err = j.clients.EC2.DescribeInstancesPages(&ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("tag-key"), Values: []*string{aws.String(j.config.TagKey)}}},
+	}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				name := ec2Tags(instance.Tags)[j.config.TagKey]
+				if _, ok := environments[name]; ok && instance.LaunchTime != nil {
+					launched[name] = earliest(launched[name], *instance.LaunchTime)
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("EC2: Cannot get list of instances: %w", err)
+	}
+
+	result := make([]Environment, 0, len(environments))
+	for name, env := range environments {
+		if env.Created.IsZero() {
+			env.Created = launched[name]
+		}
+		result = append(result, *env)
+	}
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].Name < result[k].Name
+	})
+	return result, nil
+}
+
+// lists resource groups named *-rg which select resources by the tag key, returns group names by environment name
+func (j *Janitor) listGroups() (map[string]string, error) {
+	names := make([]string, 0)
+	err := j.clients.ResourceGroups.ListGroupsPages(&resourcegroups.ListGroupsInput{},
+		func(page *resourcegroups.ListGroupsOutput, lastPage bool) bool {
+			for _, group := range page.GroupIdentifiers {
+				if name := aws.StringValue(group.GroupName); strings.HasSuffix(name, groupSuffix) {
+					names = append(names, name)
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("Resource group: Cannot get list of groups: %w", err)
+	}
+
+	groups := make(map[string]string)
+	for _, groupName := range names {
+		output, err := j.clients.ResourceGroups.GetGroupQuery(&resourcegroups.GetGroupQueryInput{
+			Group: aws.String(groupName),
+		})
+		if err != nil {
+			log.Println("Resource group: Cannot get query of group ", groupName, ": ", err)
+			continue
+		}
+		if name, ok := environmentOfQuery(output.GroupQuery, j.config.TagKey); ok {
+			groups[name] = groupName
+		}
+	}
+	return groups, nil
+}
+
+// returns environment name selected by tag based group query, false if query does not select resources by the tag key
+func environmentOfQuery(groupQuery *resourcegroups.GroupQuery, tagKey string) (string, bool) {
+	if groupQuery == nil || groupQuery.ResourceQuery == nil ||
+		aws.StringValue(groupQuery.ResourceQuery.Type) != resourcegroups.QueryTypeTagFilters10 {
+		return "", false
+	}
+
+	var query struct {
+		TagFilters []struct {
+			Key    string
+			Values []string
+		}
+	}
+	if err := json.Unmarshal([]byte(aws.StringValue(groupQuery.ResourceQuery.Query)), &query); err != nil {
+		log.Println("Resource group: Cannot parse query of group ", aws.StringValue(groupQuery.GroupName), ": ", err)
+		return "", false
+	}
+	for _, filter := range query.TagFilters {
+		if filter.Key == tagKey && len(filter.Values) == 1 {
+			return filter.Values[0], true
+		}
+	}
+	return "", false
+}
+
+// parses creation tag of resource, returns zero time if there is no valid tag
+func (j *Janitor) createdFromTag(resource string, tags map[string]string) time.Time {
+	value, ok := tags[j.config.CreatedTagKey]
+	if !ok {
+		return time.Time{}
+	}
+	created, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Println("Janitor: Ignoring invalid ", j.config.CreatedTagKey, " tag of ", resource, ": ", value)
+		return time.Time{}
+	}
+	return created
+}
+
+// Sweep removes environments older than TTL which are not on the allow-list. Environments with unknown age are kept.
+// Failure of a single environment does not stop removal of others, it is recorded in the report.
+func (j *Janitor) Sweep() (*JanitorReport, error) {
+	environments, err := j.Environments()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &JanitorReport{}
+	at := now()
+	for _, env := range environments {
+		sweep := Sweep{Environment: env, Action: j.action(env, at)}
+		log.Println("Janitor: Environment ", env.Name, " age ", env.Age(at), ": ", sweep.Action)
+		if sweep.Action == ActionRemove {
+			sweep.Report, sweep.Err = j.reap(env)
+		}
+		report.Sweeps = append(report.Sweeps, sweep)
+	}
+	return report, nil
+}
+
+// decides what to do with environment
+func (j *Janitor) action(env Environment, at time.Time) string {
+	switch {
+	case j.config.allowed(env.Name):
+		return ActionAllowed
+	case env.Created.IsZero():
+		return ActionUnknownAge
+	case env.Age(at) < j.config.TTL:
+		return ActionKeep
+	default:
+		return ActionRemove
+	}
+}
+
+// removes single environment with reaper
+func (j *Janitor) reap(env Environment) (*Report, error) {
+	r, err := NewWithClients(Config{
+		GroupName:          env.GroupName,
+		TagKey:             j.config.TagKey,
+		TagValue:           env.Name,
+		Region:             j.config.Region,
+		DryRun:             j.config.DryRun,
+		Workers:            j.config.Workers,
+		RequestsPerSecond:  j.config.RequestsPerSecond,
+		TerminationTimeout: j.config.TerminationTimeout,
+	}, j.clients)
+	if err != nil {
+		return nil, err
+	}
+	return r.Run()
+}
+
+func ec2Tags(tags []*ec2.Tag) map[string]string {
+	result := make(map[string]string)
+	for _, tag := range tags {
+		result[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return result
+}
+
+// returns earlier of times, zero time is treated as unknown
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,141 @@
+package reaper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/awsfake"
+)
+
+func newTestJanitor(t *testing.T, fake testAWS, config JanitorConfig) *Janitor {
+	retryDelay = 0
+	config.TagKey = testTagKey
+	config.Region = awsfake.Region
+	config.RequestsPerSecond = 1000
+	j, err := NewJanitorWithClients(config, Clients{EC2: fake.ec2, ResourceGroups: fake.resourceGroups, Tagging: fake.tagging})
+	if err != nil {
+		t.Fatal("Cannot create janitor: ", err)
+	}
+	return j
+}
+
+func TestSweepShouldRemoveOnlyExpiredEnvironments(t *testing.T) {
+	// given
+	current := time.Date(2020, 12, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	fake := newTestAWS()
+	environments := make(map[string]awsfake.Environment)
+	for name, launched := range map[string]time.Duration{"old": 100 * time.Hour, "young": time.Hour, "kept": 100 * time.Hour, "tagged": time.Hour} {
+		environments[name] = awsfake.AddEnvironment(fake.ec2, testTagKey, name)
+		fake.ec2.SetLaunchTime(environments[name].Instance, current.Add(-launched))
+	}
+	fake.ec2.Tag(environments["tagged"].Vpc, awsfake.Tags(DefaultCreatedTagKey, current.Add(-80*time.Hour).Format(time.RFC3339))...)
+	for _, name := range []string{"old", "young", "kept", "tagged", "orphan"} {
+		fake.resourceGroups.AddGroup(name+"-rg", testTagKey, name)
+	}
+	fake.resourceGroups.AddGroup("foreign-rg", "owner", "someone")
+	// resources tagged by other tooling without resource group of module
+	environments["other"] = awsfake.AddEnvironment(fake.ec2, testTagKey, "other")
+	fake.ec2.SetLaunchTime(environments["other"].Instance, current.Add(-100*time.Hour))
+	j := newTestJanitor(t, fake, JanitorConfig{TTL: 72 * time.Hour, AllowList: []string{"kep*"}})
+
+	// when
+	report, err := j.Sweep()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if report.Failed() {
+		t.Error("Expected no failures, got ", report.Sweeps)
+	}
+	expectedActions := map[string]string{
+		"old":    ActionRemove,
+		"young":  ActionKeep,
+		"kept":   ActionAllowed,
+		"tagged": ActionRemove,
+		"orphan": ActionUnknownAge,
+	}
+	if len(report.Sweeps) != len(expectedActions) {
+		t.Error("Expected ", len(expectedActions), " environments, got ", report.Sweeps)
+	}
+	for _, sweep := range report.Sweeps {
+		if sweep.Action != expectedActions[sweep.Name] {
+			t.Error("Expected action ", expectedActions[sweep.Name], " for ", sweep.Name, " got ", sweep.Action)
+		}
+	}
+	for name, env := range environments {
+		for _, id := range env.IDs() {
+			if removed := !fake.ec2.Exists(id); removed != (expectedActions[name] == ActionRemove) {
+				t.Error("Expected resource ", id, " of ", name, " to be removed: ", expectedActions[name] == ActionRemove)
+			}
+		}
+	}
+	if fake.resourceGroups.GroupExists("old-rg") {
+		t.Error("Expected resource group of removed environment to be removed")
+	}
+	if !fake.resourceGroups.GroupExists("orphan-rg") || !fake.resourceGroups.GroupExists("foreign-rg") {
+		t.Error("Expected resource groups of other environments to be kept")
+	}
+}
+
+func TestSweepShouldNotRemoveAnythingInDryRun(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "old")
+	fake.ec2.SetLaunchTime(env.Instance, time.Now().Add(-100*time.Hour))
+	fake.resourceGroups.AddGroup("old-rg", testTagKey, "old")
+	j := newTestJanitor(t, fake, JanitorConfig{TTL: time.Hour, DryRun: true})
+
+	// when
+	report, err := j.Sweep()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if len(report.Sweeps) != 1 || report.Sweeps[0].Action != ActionRemove || report.Sweeps[0].Report == nil {
+		t.Fatal("Expected environment to be selected for removal, got ", report.Sweeps)
+	}
+	for _, id := range env.IDs() {
+		if !fake.ec2.Exists(id) {
+			t.Error("Expected resource to be kept in dry run: ", id)
+		}
+	}
+}
+
+func TestEnvironmentOfQueryShouldUseTagFilterWithTagKey(t *testing.T) {
+	tests := []struct {
+		query        string
+		expectedName string
+		expectedOk   bool
+	}{
+		{`{"ResourceTypeFilters":["AWS::EC2::VPC"],"TagFilters":[{"Key":"resource_group","Values":["epiphany"]}]}`, "epiphany", true},
+		{`{"ResourceTypeFilters":["AWS::EC2::VPC"],"TagFilters":[{"Key":"owner","Values":["epiphany"]}]}`, "", false},
+		{`{"TagFilters":[{"Key":"resource_group","Values":["a","b"]}]}`, "", false},
+		{`not json`, "", false},
+	}
+	for _, tt := range tests {
+		// given
+		groupQuery := &resourcegroups.GroupQuery{
+			GroupName: aws.String("epiphany-rg"),
+			ResourceQuery: &resourcegroups.ResourceQuery{
+				Type:  aws.String(resourcegroups.QueryTypeTagFilters10),
+				Query: aws.String(tt.query),
+			},
+		}
+
+		// when
+		name, ok := environmentOfQuery(groupQuery, testTagKey)
+
+		// then
+		if name != tt.expectedName || ok != tt.expectedOk {
+			t.Error("Expected ", tt.expectedName, "/", tt.expectedOk, " for ", tt.query, " got ", name, "/", ok)
+		}
+	}
+}
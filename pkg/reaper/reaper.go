@@ -0,0 +1,321 @@
+// Package reaper removes AWS resources which were left behind by an awsbi
+// environment that could not be destroyed properly (e.g. when terraform
+// destroy failed half-way).
+package reaper
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/resourcegroups/resourcegroupsiface"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
+)
+
+const (
+	retries = 30
+	// DefaultWorkers is the default number of resources removed at the same time
+	DefaultWorkers = 8
+	// DefaultRequestsPerSecond is the default rate of AWS API calls made by reaper
+	DefaultRequestsPerSecond = 10
+	// DefaultTerminationTimeout is the default time of waiting for termination of single instance or nat gateway
+	DefaultTerminationTimeout = 10 * time.Minute
+)
+
+// initial and maximum delay between retries and delay between checks of instance state,
+// variables so tests don't have to wait
+var (
+	retryDelay           = time.Second
+	maxRetryDelay        = 30 * time.Second
+	terminationPollDelay = 15 * time.Second
+)
+
+// resource types handled by reaper, order is used only to present resources in stable order,
+// removal order comes from dependencies between discovered resources
+var resourcesTypes = []string{"Instance", "Volume", "NetworkInterface", "SecurityGroup", "NatGateway", "EIP", "InternetGateway", "RouteTable", "Subnet", "VPC", "KeyPair", "ResourceGroup"}
+
+// Config describes which environment should be reaped
+type Config struct {
+	// GroupName is the name of resource group created by module (<name>-rg)
+	GroupName string
+	// TagKey and TagValue identify resources that belong to the environment
+	TagKey   string
+	TagValue string
+	// KeyPairPrefix is the key_name_prefix of key pair created by module, defaults to TagValue + "-kp".
+	// Key pairs are discovered by tag and by this prefix, as AWS appends random suffix to the name.
+	KeyPairPrefix string
+	Region        string
+	// DryRun makes Run only log resources that would be deleted
+	DryRun bool
+	// Workers limits number of resources removed at the same time, defaults to DefaultWorkers
+	Workers int
+	// RequestsPerSecond limits rate of AWS API calls shared by all workers, defaults to DefaultRequestsPerSecond
+	RequestsPerSecond float64
+	// TerminationTimeout limits waiting for termination of instance and deletion of nat gateway, defaults to
+	// DefaultTerminationTimeout
+	TerminationTimeout time.Duration
+}
+
+// Resource is a single AWS resource discovered as belonging to the environment
+type Resource struct {
+	Type string
+	ID   string
+	ARN  string
+}
+
+func (r Resource) String() string {
+	if r.ARN != "" {
+		return r.ARN
+	}
+	return r.Type + "/" + r.ID
+}
+
+// Clients holds AWS API clients used by reaper, interfaces allow to replace them with fakes in tests
+type Clients struct {
+	EC2            ec2iface.EC2API
+	ResourceGroups resourcegroupsiface.ResourceGroupsAPI
+	Tagging        resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
+}
+
+// Reaper discovers and removes resources of a single environment
+type Reaper struct {
+	config  Config
+	clients Clients
+	// groupFound is set by Discover when resource group exists
+	groupFound bool
+}
+
+// New validates config and creates reaper with AWS clients for the configured region. Requests of clients
+// are rate limited and retried with exponential backoff when AWS rejects them because of request rate.
+func New(config Config) (*Reaper, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	newSession, err := limitedSession(aws.NewConfig().WithRegion(config.Region), newLimiter(config.RequestsPerSecond, config.Workers))
+	if err != nil {
+		return nil, err
+	}
+	return NewWithClients(config, sessionClients(newSession))
+}
+
+// NewWithClients validates config and creates reaper using provided AWS clients, which are used as they are
+// (RequestsPerSecond applies only to clients created by New)
+func NewWithClients(config Config, clients Clients) (*Reaper, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if clients.EC2 == nil || clients.ResourceGroups == nil || clients.Tagging == nil {
+		return nil, fmt.Errorf("all AWS clients are required")
+	}
+
+	return &Reaper{config: config, clients: clients}, nil
+}
+
+// validates config and sets default values
+func (c *Config) validate() error {
+	if c.GroupName == "" {
+		return fmt.Errorf("resource group name is required")
+	}
+	if c.TagKey == "" || c.TagValue == "" {
+		return fmt.Errorf("tag key and tag value are required")
+	}
+	if c.Region == "" {
+		return fmt.Errorf("region is required")
+	}
+	if c.KeyPairPrefix == "" {
+		c.KeyPairPrefix = c.TagValue + "-kp"
+	}
+	if c.Workers <= 0 {
+		c.Workers = DefaultWorkers
+	}
+	if c.RequestsPerSecond <= 0 {
+		c.RequestsPerSecond = DefaultRequestsPerSecond
+	}
+	if c.TerminationTimeout <= 0 {
+		c.TerminationTimeout = DefaultTerminationTimeout
+	}
+	return nil
+}
+
+// Discover lists resources which belong to the environment. Resources are listed from resource group,
+// when the group does not exist (e.g. environment was created only partially) they are discovered by tag.
+// Detached network interfaces in subnets of environment and unattached tagged volumes are listed as well,
+// as they block removal of subnets and VPC. So are network interfaces and tagged volumes attached to instances
+// of environment which are not deleted on termination, they are removed after their instances.
+func (r *Reaper) Discover() ([]Resource, error) {
+	resources, err := r.listGroupResources()
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.groupFound {
+		log.Println("Resource group: Falling back to discovery by tag ", r.config.TagKey, "=", r.config.TagValue)
+		resources, err = listTaggedResources(r.clients.Tagging, r.config.TagKey, r.config.TagValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	addresses, err := describeAddresses(r.clients.EC2, r.config.TagKey, r.config.TagValue)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPairs, err := describeKeyPairs(r.clients.EC2, r.config.TagKey, r.config.TagValue, r.config.KeyPairPrefix)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Key Pair: Found ", len(keyPairs), " key pairs.")
+
+	subnetIDs := make([]string, 0)
+	instanceIDs := make([]string, 0)
+	for _, resource := range resources {
+		switch resource.Type {
+		case "Subnet":
+			subnetIDs = append(subnetIDs, resource.ID)
+		case "Instance":
+			instanceIDs = append(instanceIDs, resource.ID)
+		}
+	}
+	networkInterfaces, err := describeNetworkInterfaces(r.clients.EC2, r.config.TagKey, r.config.TagValue, subnetIDs, instanceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := describeVolumes(r.clients.EC2, r.config.TagKey, r.config.TagValue, instanceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	resources = appendMissing(resources, addresses...)
+	resources = appendMissing(resources, networkInterfaces...)
+	resources = appendMissing(resources, volumes...)
+	return appendMissing(resources, keyPairs...), nil
+}
+
+// Run removes all resources which belong to the environment (including key pairs), then removes resource group.
+// Resources are removed in order of dependencies between them and independent resources are removed in parallel.
+// Failure to remove single resource does not stop removal of resources independent from it, all failures are
+// collected in returned report. Error is returned only when resources could not be discovered.
+func (r *Reaper) Run() (*Report, error) {
+	resources, err := r.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	g := newGraph(resources)
+	if err := addDependencies(r.clients.EC2, g); err != nil {
+		return nil, err
+	}
+
+	steps, err := g.order()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	report.discovered(resources...)
+	for _, resource := range g.excluded {
+		report.skipped(resource)
+	}
+
+	if r.config.DryRun {
+		for i, step := range steps {
+			for _, resource := range step {
+				log.Println("Dry run: step ", i+1, ": would remove: ", resource)
+			}
+		}
+		for _, resource := range g.excluded {
+			log.Println("Dry run: would skip: ", resource)
+		}
+		log.Println("Dry run: would remove resource group: ", r.config.GroupName)
+		return report, nil
+	}
+
+	errs := g.walk(r.config.Workers, r.remove)
+	for _, step := range steps {
+		for _, resource := range step {
+			if err, ok := errs[resource.ID]; ok && err == errBlocked {
+				report.failed(err, resource, "skip")
+			} else if ok {
+				report.failed(err, resource, "remove")
+			} else {
+				report.removed(resource)
+			}
+		}
+	}
+
+	// resource group is kept when anything is left, so that reaper can be run again
+	if !report.Failed() && r.groupFound {
+		group := Resource{Type: "ResourceGroup", ID: r.config.GroupName}
+		report.discovered(group)
+		if err := removeResourceGroup(r.clients.ResourceGroups, r.config.GroupName); err != nil {
+			report.failed(err, group, "delete")
+		} else {
+			report.removed(group)
+		}
+	}
+
+	return report, nil
+}
+
+// removes single resource, resource which does not exist anymore is treated as removed as
+// resources discovered by tag can be already deleted
+func (r *Reaper) remove(resource Resource) error {
+	err := r.removeByType(resource)
+	if isNotFound(err) {
+		log.Println("Resource not found, already removed: ", resource)
+		return nil
+	}
+	return err
+}
+
+// removes single resource using method appropriate for its type
+func (r *Reaper) removeByType(resource Resource) error {
+	switch resource.Type {
+	case "Instance":
+		return removeEc2(r.clients.EC2, resource, r.config.TerminationTimeout)
+	case "SecurityGroup":
+		return removeSecurityGroup(r.clients.EC2, resource)
+	case "NatGateway":
+		log.Println("Nat Gateway: ngIdToRemove: ", resource.ID)
+		return removeSingleNatGatewayWithRetries(r.clients.EC2, resource, r.config.TerminationTimeout)
+	case "EIP":
+		return releaseAddress(r.clients.EC2, resource)
+	case "InternetGateway":
+		return removeInternetGateway(r.clients.EC2, resource)
+	case "RouteTable":
+		return removeRouteTable(r.clients.EC2, resource)
+	case "Subnet":
+		return removeSubnet(r.clients.EC2, resource)
+	case "VPC":
+		return removeVpc(r.clients.EC2, resource)
+	case "KeyPair":
+		return removeKeyPair(r.clients.EC2, resource)
+	case "NetworkInterface":
+		return removeNetworkInterface(r.clients.EC2, resource)
+	case "Volume":
+		return removeVolume(r.clients.EC2, resource)
+	}
+	return fmt.Errorf("unsupported resource type: %s", resource.Type)
+}
+
+// appends resources which are not already on the list
+func appendMissing(resources []Resource, toAdd ...Resource) []Resource {
+	for _, candidate := range toAdd {
+		found := false
+		for _, resource := range resources {
+			if resource.Type == candidate.Type && resource.ID == candidate.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			resources = append(resources, candidate)
+		}
+	}
+	return resources
+}
@@ -0,0 +1,441 @@
+package reaper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/awsfake"
+)
+
+const testTagKey = "resource_group"
+
+type testAWS struct {
+	ec2            *awsfake.EC2
+	resourceGroups *awsfake.ResourceGroups
+	tagging        *awsfake.Tagging
+}
+
+func newTestAWS() testAWS {
+	ec2Fake := awsfake.NewEC2()
+	return testAWS{
+		ec2:            ec2Fake,
+		resourceGroups: awsfake.NewResourceGroups(ec2Fake),
+		tagging:        awsfake.NewTagging(ec2Fake),
+	}
+}
+
+func newTestReaper(t *testing.T, fake testAWS, name string) *Reaper {
+	retryDelay = 0
+	r, err := NewWithClients(Config{
+		GroupName: name + "-rg",
+		TagKey:    testTagKey,
+		TagValue:  name,
+		Region:    awsfake.Region,
+		// fakes don't limit request rate
+		RequestsPerSecond: 1000,
+	}, Clients{EC2: fake.ec2, ResourceGroups: fake.resourceGroups, Tagging: fake.tagging})
+	if err != nil {
+		t.Fatal("Cannot create reaper: ", err)
+	}
+	return r
+}
+
+func indexOf(calls []string, call string) int {
+	for i, c := range calls {
+		if c == call {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRunShouldRemoveEnvironmentInDependencyOrder(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	other := awsfake.AddEnvironment(fake.ec2, testTagKey, "other")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if report.Failed() {
+		t.Error("Expected no failures, got ", report.Failures)
+	}
+	for _, id := range env.IDs() {
+		if fake.ec2.Exists(id) {
+			t.Error("Expected resource to be removed: ", id)
+		}
+	}
+	for _, id := range other.IDs() {
+		if !fake.ec2.Exists(id) {
+			t.Error("Expected resource of other environment to be left: ", id)
+		}
+	}
+	if fake.resourceGroups.GroupExists("test-rg") {
+		t.Error("Expected resource group to be removed")
+	}
+
+	calls := fake.ec2.Calls()
+	for _, order := range [][2]string{
+		{"TerminateInstances " + env.Instance, "DeleteSecurityGroup " + env.SecurityGroup},
+		{"DeleteNatGateway " + env.NatGateway, "ReleaseAddress " + env.Address},
+		{"DeleteNatGateway " + env.NatGateway, "DetachInternetGateway " + env.InternetGateway},
+		{"DeleteRouteTable " + env.PublicRT, "DeleteSubnet " + env.PublicSubnet},
+		{"DeleteSubnet " + env.PrivateSubnet, "DeleteVpc " + env.Vpc},
+		{"DeleteInternetGateway " + env.InternetGateway, "DeleteVpc " + env.Vpc},
+	} {
+		first, second := indexOf(calls, order[0]), indexOf(calls, order[1])
+		if first < 0 || second < 0 || first > second {
+			t.Error("Expected ", order[0], " before ", order[1], " got calls ", calls)
+		}
+	}
+}
+
+func TestRunShouldContinueAfterFailureAndReportIt(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	fake.ec2.FailOn("DeleteSubnet", env.PrivateSubnet, awsfake.NewError("DependencyViolation"))
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	codes := make(map[string]string)
+	for _, failure := range report.Failures {
+		codes[failure.Resource.ID] = failure.Code
+		if failure.Resource.ID == env.Vpc && !errors.Is(failure, errBlocked) {
+			t.Error("Expected VPC to be blocked, got ", failure)
+		}
+	}
+	if len(codes) != 2 || codes[env.PrivateSubnet] != "DependencyViolation" {
+		t.Error("Expected failures of private subnet and VPC, got ", report.Failures)
+	}
+	if _, ok := codes[env.Vpc]; !ok {
+		t.Error("Expected failure of VPC, got ", report.Failures)
+	}
+	for _, id := range []string{env.Instance, env.NatGateway, env.PublicSubnet, env.KeyPair} {
+		if fake.ec2.Exists(id) {
+			t.Error("Expected resource to be removed despite failure: ", id)
+		}
+	}
+	if !fake.resourceGroups.GroupExists("test-rg") {
+		t.Error("Expected resource group to be kept when something failed")
+	}
+}
+
+func TestRunShouldTreatAlreadyReleasedAddressAsRemoved(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	fake.ec2.FailOn("ReleaseAddress", env.Address, awsfake.NewError("InvalidAllocationID.NotFound"))
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if report.Failed() {
+		t.Error("Expected no failures, got ", report.Failures)
+	}
+	if report.RemovedCount()["EIP"] != 1 {
+		t.Error("Expected EIP to be reported as removed, got ", report.Removed)
+	}
+}
+
+func TestDiscoverShouldFollowResourceGroupPages(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	fake.resourceGroups.PageSize = 2
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	resources, err := r.Discover()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if len(resources) != len(env.IDs()) {
+		t.Error("Expected ", len(env.IDs()), " resources, got ", resources)
+	}
+	if len(fake.tagging.Calls()) != 0 {
+		t.Error("Expected no tag based discovery when resource group exists")
+	}
+}
+
+func TestRunShouldFallBackToTagsWhenGroupIsMissing(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if report.Failed() {
+		t.Error("Expected no failures, got ", report.Failures)
+	}
+	if len(fake.tagging.Calls()) == 0 {
+		t.Error("Expected tag based discovery when resource group is missing")
+	}
+	for _, id := range env.IDs() {
+		if fake.ec2.Exists(id) {
+			t.Error("Expected resource to be removed: ", id)
+		}
+	}
+}
+
+func TestRunShouldRetryAddressReleaseOnAuthFailure(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	authFailure := awsfake.NewError("AuthFailure")
+	fake.ec2.FailOn("ReleaseAddress", env.Address, authFailure, authFailure)
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if report.Failed() {
+		t.Error("Expected no failures, got ", report.Failures)
+	}
+	if fake.ec2.Exists(env.Address) {
+		t.Error("Expected address to be released after retries")
+	}
+}
+
+func TestRunShouldRemoveDetachedNetworkInterfacesAndVolumesBeforeSubnets(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	fake.ec2.AddNetworkInterface(&ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String("eni-leftover"),
+		SubnetId:           aws.String(env.PrivateSubnet),
+		VpcId:              aws.String(env.Vpc),
+		Groups:             []*ec2.GroupIdentifier{{GroupId: aws.String(env.SecurityGroup)}},
+	})
+	fake.ec2.AddVolume(&ec2.Volume{VolumeId: aws.String("vol-leftover"), Tags: awsfake.Tags(testTagKey, "test")})
+	fake.ec2.AddVolume(&ec2.Volume{VolumeId: aws.String("vol-other"), Tags: awsfake.Tags(testTagKey, "other")})
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if report.Failed() {
+		t.Error("Expected no failures, got ", report.Failures)
+	}
+	if fake.ec2.Exists("eni-leftover") || fake.ec2.Exists("vol-leftover") {
+		t.Error("Expected leftover network interface and volume to be removed")
+	}
+	if !fake.ec2.Exists("vol-other") {
+		t.Error("Expected volume of other environment to be kept")
+	}
+	calls := fake.ec2.Calls()
+	for _, later := range []string{"DeleteSubnet " + env.PrivateSubnet, "DeleteSecurityGroup " + env.SecurityGroup} {
+		if indexOf(calls, "DeleteNetworkInterface eni-leftover") > indexOf(calls, later) {
+			t.Error("Expected network interface to be removed before ", later, " got calls ", calls)
+		}
+	}
+}
+
+func TestRunShouldRemoveVolumeAndNetworkInterfaceDetachedByTerminationOfInstance(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	fake.ec2.AddNetworkInterface(&ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String("eni-retained"),
+		SubnetId:           aws.String(env.PrivateSubnet),
+		VpcId:              aws.String(env.Vpc),
+		Status:             aws.String(ec2.NetworkInterfaceStatusInUse),
+		Attachment:         &ec2.NetworkInterfaceAttachment{InstanceId: aws.String(env.Instance), DeleteOnTermination: aws.Bool(false)},
+	})
+	for _, volume := range []struct {
+		id                  string
+		deleteOnTermination bool
+	}{{"vol-root", true}, {"vol-retained", false}} {
+		fake.ec2.AddVolume(&ec2.Volume{
+			VolumeId:    aws.String(volume.id),
+			State:       aws.String(ec2.VolumeStateInUse),
+			Attachments: []*ec2.VolumeAttachment{{InstanceId: aws.String(env.Instance), DeleteOnTermination: aws.Bool(volume.deleteOnTermination)}},
+			Tags:        awsfake.Tags(testTagKey, "test"),
+		})
+	}
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if report.Failed() {
+		t.Error("Expected no failures, got ", report.Failures)
+	}
+	for _, id := range []string{"eni-retained", "vol-root", "vol-retained", env.PrivateSubnet} {
+		if fake.ec2.Exists(id) {
+			t.Error("Expected ", id, " to be removed")
+		}
+	}
+	calls := fake.ec2.Calls()
+	for _, call := range []string{"DeleteNetworkInterface eni-retained", "DeleteVolume vol-retained"} {
+		if indexOf(calls, call) < indexOf(calls, "TerminateInstances "+env.Instance) {
+			t.Error("Expected ", call, " after termination of instance, got calls ", calls)
+		}
+	}
+}
+
+func TestRunShouldStopWaitingForTerminationAfterTimeout(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	fake.ec2.StuckShuttingDown = true
+	retryDelay = 0
+	r, err := NewWithClients(Config{
+		GroupName:          "test-rg",
+		TagKey:             testTagKey,
+		TagValue:           "test",
+		Region:             awsfake.Region,
+		RequestsPerSecond:  1000,
+		TerminationTimeout: 50 * time.Millisecond,
+	}, Clients{EC2: fake.ec2, ResourceGroups: fake.resourceGroups, Tagging: fake.tagging})
+	if err != nil {
+		t.Fatal("Cannot create reaper: ", err)
+	}
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	failures := make(map[string]*Error)
+	for _, failure := range report.Failures {
+		failures[failure.Resource.ID] = failure
+	}
+	if failure := failures[env.Instance]; failure == nil || failure.Action != "wait for termination" {
+		t.Error("Expected instance to fail waiting for termination, got ", report.Failures)
+	}
+	for _, id := range []string{env.SecurityGroup, env.PublicSubnet} {
+		if failure := failures[id]; failure == nil || !errors.Is(failure, errBlocked) {
+			t.Error("Expected ", id, " to be blocked by instance, got ", failure)
+		}
+	}
+	if indexOf(fake.ec2.Calls(), "DeleteSecurityGroup "+env.SecurityGroup) >= 0 {
+		t.Error("Expected no attempt to remove security group used by instance")
+	}
+}
+
+func TestRunShouldStopWaitingForDeletionOfNatGatewayAfterTimeout(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	fake.ec2.StuckDeleting = true
+	retryDelay = 0
+	r, err := NewWithClients(Config{
+		GroupName:          "test-rg",
+		TagKey:             testTagKey,
+		TagValue:           "test",
+		Region:             awsfake.Region,
+		RequestsPerSecond:  1000,
+		TerminationTimeout: 50 * time.Millisecond,
+	}, Clients{EC2: fake.ec2, ResourceGroups: fake.resourceGroups, Tagging: fake.tagging})
+	if err != nil {
+		t.Fatal("Cannot create reaper: ", err)
+	}
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	failures := make(map[string]*Error)
+	for _, failure := range report.Failures {
+		failures[failure.Resource.ID] = failure
+	}
+	if failure := failures[env.NatGateway]; failure == nil || failure.Action != "wait for deletion" {
+		t.Error("Expected nat gateway to fail waiting for deletion, got ", report.Failures)
+	}
+	if failure := failures[env.Address]; failure == nil || !errors.Is(failure, errBlocked) {
+		t.Error("Expected address to be blocked by nat gateway, got ", failure)
+	}
+	if indexOf(fake.ec2.Calls(), "ReleaseAddress "+env.Address) >= 0 {
+		t.Error("Expected no attempt to release address used by nat gateway")
+	}
+}
+
+func TestRunShouldSkipMainRouteTableRemovedTogetherWithVpc(t *testing.T) {
+	// given
+	fake := newTestAWS()
+	env := awsfake.AddEnvironment(fake.ec2, testTagKey, "test")
+	fake.ec2.Tag(env.MainRT, awsfake.Tags(testTagKey, "test")...)
+	fake.resourceGroups.AddGroup("test-rg", testTagKey, "test")
+	r := newTestReaper(t, fake, "test")
+
+	// when
+	report, err := r.Run()
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if report.Failed() {
+		t.Error("Expected no failures, got ", report.Failures)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].ID != env.MainRT {
+		t.Error("Expected main route table to be skipped, got ", report.Skipped)
+	}
+	for _, removed := range report.Removed {
+		if removed.ID == env.MainRT {
+			t.Error("Expected main route table not to be reported as removed")
+		}
+	}
+	if indexOf(fake.ec2.Calls(), "DeleteRouteTable "+env.MainRT) >= 0 {
+		t.Error("Expected no attempt to remove main route table")
+	}
+	if fake.ec2.Exists(env.Vpc) {
+		t.Error("Expected VPC to be removed")
+	}
+}
@@ -0,0 +1,104 @@
+package reaper
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// Report summarizes results of Run
+type Report struct {
+	// Discovered holds number of discovered resources per resource type
+	Discovered map[string]int
+	// Removed holds resources which were removed successfully
+	Removed []Resource
+	// Skipped holds resources which are not removed by reaper as they are removed together with other resources
+	// (e.g. main route table with its VPC)
+	Skipped []Resource
+	// Failures holds errors of resources which could not be removed
+	Failures []*Error
+}
+
+// Failed returns true if anything could not be removed
+func (r *Report) Failed() bool {
+	return len(r.Failures) > 0
+}
+
+// RemovedCount returns number of removed resources per resource type
+func (r *Report) RemovedCount() map[string]int {
+	counts := make(map[string]int)
+	for _, resource := range r.Removed {
+		counts[resource.Type]++
+	}
+	return counts
+}
+
+func (r *Report) discovered(resources ...Resource) {
+	if r.Discovered == nil {
+		r.Discovered = make(map[string]int)
+	}
+	for _, resource := range resources {
+		r.Discovered[resource.Type]++
+	}
+}
+
+func (r *Report) removed(resource Resource) {
+	r.Removed = append(r.Removed, resource)
+}
+
+func (r *Report) skipped(resource Resource) {
+	r.Skipped = append(r.Skipped, resource)
+}
+
+func (r *Report) failed(err error, resource Resource, action string) {
+	if e, ok := err.(*Error); ok {
+		r.Failures = append(r.Failures, e)
+		return
+	}
+	r.Failures = append(r.Failures, newError(resource, action, 0, err))
+}
+
+// Print writes report in tabular form
+func (r *Report) Print(w io.Writer) {
+	removedCount := r.RemovedCount()
+	types := make([]string, 0, len(r.Discovered))
+	for resourceType := range r.Discovered {
+		types = append(types, resourceType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return typeRank(types[i]) < typeRank(types[j])
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tDISCOVERED\tREMOVED")
+	for _, resourceType := range types {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", resourceType, r.Discovered[resourceType], removedCount[resourceType])
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "Removed: %d, failed: %d\n", len(r.Removed), len(r.Failures))
+	for _, resource := range r.Skipped {
+		fmt.Fprintln(w, "Skipped (removed together with other resources):", resource)
+	}
+	if len(r.Failures) == 0 {
+		return
+	}
+
+	failures := make([]*Error, len(r.Failures))
+	copy(failures, r.Failures)
+	sort.SliceStable(failures, func(i, j int) bool {
+		return failures[i].Resource.String() < failures[j].Resource.String()
+	})
+
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tACTION\tCODE\tRETRIES\tERROR")
+	for _, failure := range failures {
+		code := failure.Code
+		if code == "" {
+			code = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", failure.Resource, failure.Action, code, failure.Retries, failure.Err)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,60 @@
+package reaper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestReportShouldPrintFailures(t *testing.T) {
+	// given
+	subnet := Resource{Type: "Subnet", ID: "subnet-1", ARN: "arn:aws:ec2:eu-central-1:123:subnet/subnet-1"}
+	vpc := Resource{Type: "VPC", ID: "vpc-1"}
+	report := &Report{}
+	report.removed(Resource{Type: "Instance", ID: "i-1"})
+	report.failed(newError(subnet, "delete", 0, awserr.New("DependencyViolation", "dependencies", nil)), subnet, "remove")
+	report.failed(errBlocked, vpc, "skip")
+
+	// when
+	var out bytes.Buffer
+	report.Print(&out)
+
+	// then
+	if !report.Failed() {
+		t.Error("Expected report to be failed")
+	}
+	printed := out.String()
+	for _, expected := range []string{"Removed: 1, failed: 2", subnet.ARN, "DependencyViolation", "VPC/vpc-1", "skip"} {
+		if !strings.Contains(printed, expected) {
+			t.Error("Expected report to contain ", expected, " but got:\n", printed)
+		}
+	}
+}
+
+func TestReportShouldCountDiscoveredAndRemovedPerType(t *testing.T) {
+	// given
+	report := &Report{}
+	subnets := []Resource{{Type: "Subnet", ID: "subnet-1"}, {Type: "Subnet", ID: "subnet-2"}, {Type: "Subnet", ID: "subnet-3"}}
+	report.discovered(subnets...)
+	report.discovered(Resource{Type: "VPC", ID: "vpc-1"})
+	report.removed(subnets[0])
+	report.removed(subnets[2])
+
+	// when
+	removed := report.RemovedCount()
+	var out bytes.Buffer
+	report.Print(&out)
+
+	// then
+	if report.Discovered["Subnet"] != 3 || removed["Subnet"] != 2 {
+		t.Error("Expected 3 discovered and 2 removed subnets, got ", report.Discovered["Subnet"], " and ", removed["Subnet"])
+	}
+	if report.Discovered["VPC"] != 1 || removed["VPC"] != 0 {
+		t.Error("Expected 1 discovered and 0 removed VPCs, got ", report.Discovered["VPC"], " and ", removed["VPC"])
+	}
+	if !strings.Contains(out.String(), "Subnet  3           2") {
+		t.Error("Expected report to contain subnet counts but got:\n", out.String())
+	}
+}
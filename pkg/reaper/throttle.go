@@ -0,0 +1,120 @@
+package reaper
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+)
+
+// number of retries of API call rejected because of request rate
+const throttleRetries = 8
+
+// returns delay before given retry, doubling from retryDelay up to maxRetryDelay
+func backoff(retry int) time.Duration {
+	delay := retryDelay
+	for i := 0; i < retry && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// checks if AWS rejected call because of request rate
+func isThrottling(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "RequestLimitExceeded", "Throttling", "ThrottlingException", "TooManyRequestsException":
+			return true
+		}
+	}
+	return false
+}
+
+// limiter is a token bucket shared by all API requests of session, rate <= 0 means no limit
+type limiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	return &limiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until token is available and takes it
+func (l *limiter) wait() {
+	if l.rate <= 0 {
+		return
+	}
+	for {
+		l.mutex.Lock()
+		current := time.Now()
+		l.tokens += current.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = current
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mutex.Unlock()
+			return
+		}
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mutex.Unlock()
+		time.Sleep(delay)
+	}
+}
+
+// throttleRetryer retries requests rejected because of request rate with exponential backoff, other errors are
+// returned right away
+type throttleRetryer struct{}
+
+func (throttleRetryer) MaxRetries() int {
+	return throttleRetries
+}
+
+func (throttleRetryer) ShouldRetry(r *request.Request) bool {
+	return isThrottling(r.Error)
+}
+
+func (throttleRetryer) RetryRules(r *request.Request) time.Duration {
+	delay := backoff(r.RetryCount)
+	log.Println("Throttling: Request limit exceeded, retrying in ", delay)
+	return delay
+}
+
+// limitedSession creates session whose requests take token of limiter right before they are sent, so every call,
+// page, retry and poll of waiter is limited exactly once, and are retried by throttleRetryer
+func limitedSession(config *aws.Config, l *limiter) (*session.Session, error) {
+	newSession, err := session.NewSession(request.WithRetryer(config, throttleRetryer{}))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get session: %w", err)
+	}
+	newSession.Handlers.Send.PushFrontNamed(request.NamedHandler{
+		Name: "reaper.Limiter",
+		Fn:   func(*request.Request) { l.wait() },
+	})
+	return newSession, nil
+}
+
+// returns clients of all APIs used by reaper created with session
+func sessionClients(s *session.Session) Clients {
+	return Clients{
+		EC2:            ec2.New(s),
+		ResourceGroups: resourcegroups.New(s),
+		Tagging:        resourcegroupstaggingapi.New(s),
+	}
+}
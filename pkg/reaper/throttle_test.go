@@ -0,0 +1,152 @@
+package reaper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/awsfake"
+)
+
+func TestBackoffShouldDoubleDelayUpToMaximum(t *testing.T) {
+	// given
+	retryDelay, maxRetryDelay = time.Second, 30*time.Second
+	defer func() { retryDelay = 0 }()
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+
+	for retry, delay := range expected {
+		// when
+		actual := backoff(retry)
+
+		// then
+		if actual != delay {
+			t.Error("Expected delay ", delay, " for retry ", retry, " got ", actual)
+		}
+	}
+}
+
+func TestLimiterShouldLimitRate(t *testing.T) {
+	// given
+	l := newLimiter(100, 1)
+	start := time.Now()
+
+	// when
+	for i := 0; i < 6; i++ {
+		l.wait()
+	}
+
+	// then
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Error("Expected 6 calls with rate 100/s and burst 1 to take at least 50ms, took ", elapsed)
+	}
+}
+
+// returns EC2 client of limited session sending requests to server
+func newTestEC2(t *testing.T, server *httptest.Server, l *limiter) *ec2.EC2 {
+	newSession, err := limitedSession(&aws.Config{
+		Region:      aws.String(awsfake.Region),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ec2.New(newSession)
+}
+
+// writes EC2 error response
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<Response><Errors><Error><Code>%s</Code><Message>%s</Message></Error></Errors><RequestID>1</RequestID></Response>`, code, code)
+}
+
+func TestLimitedSessionShouldRetryRequestsRejectedBecauseOfRequestRate(t *testing.T) {
+	// given
+	retryDelay = 0
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			writeError(w, http.StatusServiceUnavailable, "RequestLimitExceeded")
+			return
+		}
+		fmt.Fprint(w, `<DeleteSubnetResponse><return>true</return></DeleteSubnetResponse>`)
+	}))
+	defer server.Close()
+	client := newTestEC2(t, server, newLimiter(0, 1))
+
+	// when
+	_, err := client.DeleteSubnet(&ec2.DeleteSubnetInput{SubnetId: aws.String("subnet-1")})
+
+	// then
+	if err != nil {
+		t.Error("Expected no error, got ", err)
+	}
+	if requests != 3 {
+		t.Error("Expected 3 requests, got ", requests)
+	}
+}
+
+func TestLimitedSessionShouldNotRetryOtherErrors(t *testing.T) {
+	// given
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeError(w, http.StatusServiceUnavailable, "DependencyViolation")
+	}))
+	defer server.Close()
+	client := newTestEC2(t, server, newLimiter(0, 1))
+
+	// when
+	_, err := client.DeleteSubnet(&ec2.DeleteSubnetInput{SubnetId: aws.String("subnet-1")})
+
+	// then
+	if err == nil || requests != 1 {
+		t.Error("Expected single request failing with DependencyViolation, got ", requests, " requests and error ", err)
+	}
+}
+
+func TestWaiterShouldTakeTokenForEveryPoll(t *testing.T) {
+	// given
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		state := ec2.InstanceStateNameShuttingDown
+		if polls == 4 {
+			state = ec2.InstanceStateNameTerminated
+		}
+		fmt.Fprintf(w, `<DescribeInstancesResponse><reservationSet><item><instancesSet><item>`+
+			`<instanceId>i-1</instanceId><instanceState><name>%s</name></instanceState>`+
+			`</item></instancesSet></item></reservationSet></DescribeInstancesResponse>`, state)
+	}))
+	defer server.Close()
+	// burst covers 4 polls, fifth token would take a second
+	l := newLimiter(1, 4)
+	client := newTestEC2(t, server, l)
+	start := time.Now()
+
+	// when
+	err := client.WaitUntilInstanceTerminatedWithContext(aws.BackgroundContext(),
+		&ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice([]string{"i-1"})}, request.WithWaiterDelay(request.ConstantWaiterDelay(0)))
+
+	// then
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+	if polls != 4 {
+		t.Error("Expected 4 polls, got ", polls)
+	}
+	if l.tokens >= 1 {
+		t.Error("Expected every poll to take token, ", l.tokens, " tokens left")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Error("Expected every poll to take single token, waiter took ", elapsed)
+	}
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxTransitions is the number of most recent transitions kept in state file
+const maxTransitions = 20
+
+// now returns current time, replaced in tests
+var now = time.Now
+
+// Transition is single change of module status
+type Transition struct {
+	From    Status    `yaml:"from"`
+	To      Status    `yaml:"to"`
+	At      time.Time `yaml:"at"`
+	Version string    `yaml:"version"`
+}
+
+// transitions are legal changes of status, empty status means there is no module section yet
+var transitions = map[Status][]Status{
+	"":          {Initialized},
+	Initialized: {Initialized, Applying, Destroying},
+	Applying:    {Applied, Failed},
+	Applied:     {Initialized, Applying, Destroying},
+	Destroying:  {Destroyed, Failed},
+	Destroyed:   {Initialized},
+	Failed:      {Initialized, Applying, Destroying},
+}
+
+// commands name operations which lead to status, used in error messages
+var commands = map[Status]string{
+	Initialized: "init",
+	Applying:    "apply",
+	Applied:     "finish apply",
+	Destroying:  "destroy",
+	Destroyed:   "finish destroy",
+	Failed:      "mark module as failed",
+}
+
+// TransitionError is returned when command is not allowed in current status
+type TransitionError struct {
+	From   Status
+	To     Status
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	from := e.From
+	if from == "" {
+		from = "none"
+	}
+	return fmt.Sprintf("cannot %s (%s status is %s): %s", commands[e.To], ModuleKey, from, e.Reason)
+}
+
+// CheckTransition returns TransitionError if status cannot be changed from one value to another
+func CheckTransition(from, to Status) error {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to, Reason: reason(from, to)}
+}
+
+// explains why transition is not allowed
+func reason(from, to Status) string {
+	switch {
+	case from == "":
+		return "module is not initialized, run init first"
+	case from == Applying || from == Destroying:
+		return fmt.Sprintf("%s is in progress, if it was interrupted mark module as failed first (make mark-failed)", commands[from])
+	case to == Applied:
+		return "apply was not started"
+	case to == Destroyed:
+		return "destroy was not started"
+	case to == Failed:
+		return "neither apply nor destroy is in progress"
+	case from == Destroyed && to == Destroying:
+		return "module is already destroyed"
+	case from == Destroyed:
+		return "module is destroyed, run init first"
+	}
+	return "transition is not allowed"
+}
+
+// Status returns current status of module, empty if there is no module section
+func (f *File) Status() (Status, error) {
+	section, err := f.AWSBI()
+	if err != nil || section == nil {
+		return "", err
+	}
+	return section.Status, nil
+}
+
+// transition changes status of module section after checking it is allowed and records it
+func (f *File) transition(to Status, version string, update func(section *AWSBI)) error {
+	section, err := f.AWSBI()
+	if err != nil {
+		return err
+	}
+	if section == nil {
+		section = &AWSBI{}
+	}
+	if err := CheckTransition(section.Status, to); err != nil {
+		return err
+	}
+
+	section.Transitions = append(section.Transitions, Transition{
+		From:    section.Status,
+		To:      to,
+		At:      now().UTC().Truncate(time.Second),
+		Version: version,
+	})
+	if len(section.Transitions) > maxTransitions {
+		section.Transitions = section.Transitions[len(section.Transitions)-maxTransitions:]
+	}
+	section.Status = to
+	section.SchemaVersion = SchemaVersion
+	if update != nil {
+		update(section)
+	}
+	return f.SetAWSBI(section)
+}
@@ -0,0 +1,173 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLifecycle(t *testing.T) {
+	// given
+	f, err := Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name   string
+		change func() error
+		status Status
+	}{
+		{"init", func() error { return f.Initialize("0.0.1") }, Initialized},
+		{"start apply", func() error { return f.StartApply("0.0.1") }, Applying},
+		{"fail", func() error { return f.Fail("0.0.1") }, Failed},
+		{"retry apply", func() error { return f.StartApply("0.0.2") }, Applying},
+		{"finish apply", func() error { return f.Applied(Config{Name: "epiphany"}, "0.0.2") }, Applied},
+		{"start destroy", func() error { return f.StartDestroy("0.0.2") }, Destroying},
+		{"finish destroy", func() error { return f.Destroyed("0.0.2") }, Destroyed},
+		{"init again", func() error { return f.Initialize("0.0.2") }, Initialized},
+	}
+
+	for _, step := range steps {
+		// when
+		err := step.change()
+
+		// then
+		if err != nil {
+			t.Fatal("Expected ", step.name, " to be allowed, got ", err)
+		}
+		status, err := f.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != step.status {
+			t.Error("Expected status ", step.status, " after ", step.name, " got ", status)
+		}
+	}
+
+	section, err := f.AWSBI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(section.Transitions) != len(steps) {
+		t.Fatal("Expected ", len(steps), " transitions, got ", section.Transitions)
+	}
+	last := section.Transitions[len(steps)-1]
+	if last.From != Destroyed || last.To != Initialized || last.Version != "0.0.2" || last.At.IsZero() {
+		t.Error("Expected recorded transition from destroyed to initialized, got ", last)
+	}
+}
+
+func TestIllegalTransitions(t *testing.T) {
+	tests := []struct {
+		from   Status
+		to     Status
+		reason string
+	}{
+		{"", Applying, "run init first"},
+		{Destroyed, Applying, "run init first"},
+		{Destroyed, Destroying, "already destroyed"},
+		{Applying, Destroying, "apply is in progress"},
+		{Destroying, Initialized, "destroy is in progress"},
+		{Initialized, Applied, "apply was not started"},
+		{Applied, Destroyed, "destroy was not started"},
+		{Applied, Failed, "neither apply nor destroy"},
+		{Applying, Initialized, "apply is in progress"},
+	}
+
+	for _, test := range tests {
+		// when
+		err := CheckTransition(test.from, test.to)
+
+		// then
+		if _, ok := err.(*TransitionError); !ok {
+			t.Error("Expected transition from ", test.from, " to ", test.to, " to be refused, got ", err)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.reason) {
+			t.Error("Expected error containing ", test.reason, " got ", err)
+		}
+	}
+}
+
+func TestInitOnAppliedState(t *testing.T) {
+	// given
+	f, err := Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range []func() error{
+		func() error { return f.Initialize("0.0.1") },
+		func() error { return f.StartApply("0.0.1") },
+		func() error { return f.Applied(Config{Name: "epiphany", InstanceCount: 1}, "0.0.1") },
+	} {
+		if err := change(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// when
+	err = f.Initialize("0.0.2")
+
+	// then
+	if err != nil {
+		t.Fatal("Expected init of applied module to be allowed, got ", err)
+	}
+	section, err := f.AWSBI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section.Status != Initialized {
+		t.Error("Expected status ", Initialized, " got ", section.Status)
+	}
+	if section.Config == nil || section.Config.InstanceCount != 1 {
+		t.Error("Expected applied config to be kept for plan to compare with, got ", section.Config)
+	}
+	if err := f.StartApply("0.0.2"); err != nil {
+		t.Error("Expected apply of changed config to be allowed, got ", err)
+	}
+}
+
+func TestRefusedTransitionDoesNotChangeState(t *testing.T) {
+	// given
+	f, err := Parse([]byte("kind: state\nawsbi:\n  status: destroyed\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	err = f.StartDestroy("0.0.1")
+
+	// then
+	if err == nil {
+		t.Fatal("Expected destroy after destroyed to be refused")
+	}
+	if status, _ := f.Status(); status != Destroyed {
+		t.Error("Expected status ", Destroyed, " got ", status)
+	}
+}
+
+func TestTransitionsAreLimited(t *testing.T) {
+	// given
+	f, err := Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	for i := 0; i < maxTransitions+5; i++ {
+		if err := f.Initialize("0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// then
+	section, err := f.AWSBI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(section.Transitions) != maxTransitions {
+		t.Error("Expected ", maxTransitions, " transitions, got ", len(section.Transitions))
+	}
+	if section.Transitions[0].From != Initialized {
+		t.Error("Expected the oldest transitions to be dropped, got ", section.Transitions[0])
+	}
+}
@@ -0,0 +1,159 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigKind is the value of kind field of module config file
+	ConfigKind = ModuleKey + "-config"
+	// ConfigSchemaVersion is the version of config file layout written by this module
+	ConfigSchemaVersion = 2
+)
+
+// configFile is the module config file (awsbi-config.yml)
+type configFile struct {
+	Kind          string `yaml:"kind"`
+	SchemaVersion int    `yaml:"schema_version"`
+	Config        Config `yaml:"awsbi"`
+}
+
+// LoadConfig reads module parameters from config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file %s: %w", path, err)
+	}
+	var file configFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+	if file.Kind != ConfigKind {
+		return nil, fmt.Errorf("config file %s has kind %q, expected %q", path, file.Kind, ConfigKind)
+	}
+	return &file.Config, nil
+}
+
+// MarshalConfig returns content of config file with given module parameters, keys are always
+// written in the same order
+func MarshalConfig(config Config) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(configFile{Kind: ConfigKind, SchemaVersion: ConfigSchemaVersion, Config: config}); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Initialize marks module as initialized, other fields of module section are kept
+func (f *File) Initialize(version string) error {
+	return f.transition(Initialized, version, nil)
+}
+
+// StartApply marks module as being applied
+func (f *File) StartApply(version string) error {
+	return f.transition(Applying, version, nil)
+}
+
+// Applied marks module as applied with given parameters
+func (f *File) Applied(config Config, version string) error {
+	return f.transition(Applied, version, func(section *AWSBI) {
+		section.Config = &config
+	})
+}
+
+// StartDestroy marks module as being destroyed
+func (f *File) StartDestroy(version string) error {
+	return f.transition(Destroying, version, nil)
+}
+
+// Destroyed removes parameters and outputs from module section and marks module as destroyed
+func (f *File) Destroyed(version string) error {
+	return f.transition(Destroyed, version, func(section *AWSBI) {
+		section.Config = nil
+		section.Output = nil
+	})
+}
+
+// Fail marks module as failed after interrupted apply or destroy
+func (f *File) Fail(version string) error {
+	return f.transition(Failed, version, nil)
+}
+
+// SetOutput stores terraform outputs in module section
+func (f *File) SetOutput(output *Output) error {
+	section, err := f.AWSBI()
+	if err != nil {
+		return err
+	}
+	if section == nil {
+		return fmt.Errorf("there is no %s section in state", ModuleKey)
+	}
+	section.Output = output
+	return f.SetAWSBI(section)
+}
+
+// ParseTerraformOutput reads output of `terraform output -json`
+func ParseTerraformOutput(data []byte) (*Output, error) {
+	var outputs map[string]struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("cannot parse terraform output: %w", err)
+	}
+
+	values := make(map[string]json.RawMessage, len(outputs))
+	for name, output := range outputs {
+		values[name] = output.Value
+	}
+	flat, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	var output Output
+	if err := json.Unmarshal(flat, &output); err != nil {
+		return nil, fmt.Errorf("cannot parse terraform output: %w", err)
+	}
+	return &output, nil
+}
+
+// statusKeys are keys of module section which are not module parameters
+var statusKeys = []string{"status", "schema_version", "output", "transitions"}
+
+// ConfigValues returns module parameters stored in module section as generic values, the same way
+// as they are in config file, nil if there is no module section
+func (f *File) ConfigValues() (map[string]interface{}, error) {
+	var values map[string]interface{}
+	if ok, err := f.Section(ModuleKey, &values); !ok || err != nil {
+		return nil, err
+	}
+	for _, key := range statusKeys {
+		delete(values, key)
+	}
+	return values, nil
+}
+
+// LoadConfigValues reads module parameters from config file as generic values
+func LoadConfigValues(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file %s: %w", path, err)
+	}
+	var file struct {
+		Values map[string]interface{} `yaml:"awsbi"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+	return file.Values, nil
+}
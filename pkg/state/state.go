@@ -0,0 +1,291 @@
+// Package state reads and writes the state file (state.yml) shared by all epiphany modules.
+// Module owns only its own section of the file (awsbi), sections of other modules are preserved
+// as they are, including their order and comments.
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Kind is the value of kind field of state file
+	Kind = "state"
+	// ModuleKey is the key of section owned by this module
+	ModuleKey = "awsbi"
+	// SchemaVersion is the version of module section written by this module, older sections are
+	// upgraded by migrations
+	SchemaVersion = 2
+)
+
+// Status of module in state file
+type Status string
+
+const (
+	Initialized Status = "initialized"
+	Applying    Status = "applying"
+	Applied     Status = "applied"
+	Destroying  Status = "destroying"
+	Destroyed   Status = "destroyed"
+	Failed      Status = "failed"
+)
+
+// Subnets describes number of subnets of each kind
+type Subnets struct {
+	Private SubnetGroup `yaml:"private" json:"private"`
+	Public  SubnetGroup `yaml:"public" json:"public"`
+}
+
+// SubnetGroup describes subnets of single kind
+type SubnetGroup struct {
+	Count int `yaml:"count" json:"count"`
+}
+
+// Config holds module parameters, the same as awsbi section of config file
+type Config struct {
+	Name            string  `yaml:"name" json:"name"`
+	InstanceCount   int     `yaml:"instance_count" json:"instance_count"`
+	Region          string  `yaml:"region" json:"region"`
+	UsePublicIP     bool    `yaml:"use_public_ip" json:"use_public_ip"`
+	NatGatewayCount int     `yaml:"nat_gateway_count" json:"nat_gateway_count"`
+	Subnets         Subnets `yaml:"subnets" json:"subnets"`
+	RsaPubPath      string  `yaml:"rsa_pub_path" json:"rsa_pub_path"`
+	OS              string  `yaml:"os" json:"os"`
+	RootVolumeSize  int     `yaml:"root_volume_size,omitempty" json:"root_volume_size,omitempty"`
+}
+
+// Output holds terraform outputs of applied module
+type Output struct {
+	PrivateIP           []string `yaml:"private_ip" json:"private_ip"`
+	PublicIP            []string `yaml:"public_ip" json:"public_ip"`
+	VpcID               string   `yaml:"vpc_id" json:"vpc_id"`
+	PublicSubnetIDs     []string `yaml:"public_subnet_ids" json:"public_subnet_ids"`
+	PrivateSubnetIDs    []string `yaml:"private_subnet_ids" json:"private_subnet_ids"`
+	PrivateRouteTableID string   `yaml:"private_route_table_id" json:"private_route_table_id"`
+}
+
+// AWSBI is the section of state file owned by this module. Config is set after apply, Output after
+// terraform output is read. Transitions are the most recent changes of status. SchemaVersion is
+// the version of section layout.
+type AWSBI struct {
+	Status        Status `yaml:"status"`
+	SchemaVersion int    `yaml:"schema_version,omitempty"`
+	*Config       `yaml:",inline"`
+	Output        *Output      `yaml:"output,omitempty"`
+	Transitions   []Transition `yaml:"transitions,omitempty"`
+}
+
+// File is the state file, only kind and awsbi section are interpreted
+type File struct {
+	path string
+	root *yaml.Node
+}
+
+// Load reads state file, missing or empty file is treated as empty state
+func Load(path string) (*File, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot read state file %s: %w", path, err)
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse state file %s: %w", path, err)
+	}
+	f.path = path
+	return f, nil
+}
+
+// Parse parses content of state file, empty content is treated as empty state
+func Parse(data []byte) (*File, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("state has to be a mapping")
+	}
+	return &File{root: document.Content[0]}, nil
+}
+
+// Path returns path the file was loaded from
+func (f *File) Path() string {
+	return f.path
+}
+
+// Kind returns value of kind field, empty if there is none
+func (f *File) Kind() string {
+	if node := f.value("kind"); node != nil {
+		return node.Value
+	}
+	return ""
+}
+
+// AWSBI returns section of this module, nil if there is none
+func (f *File) AWSBI() (*AWSBI, error) {
+	node := f.value(ModuleKey)
+	if node == nil {
+		return nil, nil
+	}
+	var section AWSBI
+	if err := node.Decode(&section); err != nil {
+		return nil, fmt.Errorf("cannot decode %s section of state: %w", ModuleKey, err)
+	}
+	return &section, nil
+}
+
+// SetAWSBI replaces section of this module and sets kind of file, other sections are not changed
+func (f *File) SetAWSBI(section *AWSBI) error {
+	var node yaml.Node
+	if err := node.Encode(section); err != nil {
+		return fmt.Errorf("cannot encode %s section of state: %w", ModuleKey, err)
+	}
+	f.set("kind", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: Kind})
+	f.set(ModuleKey, &node)
+	return nil
+}
+
+// RestoreAWSBI replaces section of this module with the one from other state file as it is, other
+// sections are not changed. Section is removed when there is none in other file.
+func (f *File) RestoreAWSBI(other *File) {
+	node := other.value(ModuleKey)
+	if node == nil {
+		f.remove(ModuleKey)
+		return
+	}
+	f.set("kind", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: Kind})
+	f.set(ModuleKey, node)
+}
+
+// SectionNode returns node of top level key as it is, nil if there is no such key. Changes of the
+// node change the file.
+func (f *File) SectionNode(key string) *yaml.Node {
+	return f.value(key)
+}
+
+// Section decodes section of other module into out, returns false if there is no such section
+func (f *File) Section(key string, out interface{}) (bool, error) {
+	node := f.value(key)
+	if node == nil {
+		return false, nil
+	}
+	return true, node.Decode(out)
+}
+
+// Keys returns top level keys of state file in order
+func (f *File) Keys() []string {
+	keys := make([]string, 0, len(f.root.Content)/2)
+	for i := 0; i+1 < len(f.root.Content); i += 2 {
+		keys = append(keys, f.root.Content[i].Value)
+	}
+	return keys
+}
+
+// Bytes returns content of state file
+func (f *File) Bytes() ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(f.root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Save writes state file to the path it was loaded from
+func (f *File) Save() error {
+	return f.SaveAs(f.path)
+}
+
+// SaveAs atomically writes state file to path: content is written to temporary file in the same
+// directory which then replaces the original one, so readers never see partially written state
+func (f *File) SaveAs(path string) error {
+	data, err := f.Bytes()
+	if err != nil {
+		return fmt.Errorf("cannot encode state: %w", err)
+	}
+	return WriteFileAtomic(path, data)
+}
+
+// WriteFileAtomic writes data to temporary file next to path and renames it to path,
+// permissions of existing file are preserved
+func WriteFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("cannot set permissions of temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// returns value node of top level key, nil if there is no such key
+func (f *File) value(key string) *yaml.Node {
+	return mappingValue(f.root, key)
+}
+
+// sets value node of top level key, new keys are appended
+func (f *File) set(key string, value *yaml.Node) {
+	for i := 0; i+1 < len(f.root.Content); i += 2 {
+		if f.root.Content[i].Value == key {
+			// keep comments attached to the old value
+			value.HeadComment = f.root.Content[i+1].HeadComment
+			value.LineComment = f.root.Content[i+1].LineComment
+			f.root.Content[i+1] = value
+			return
+		}
+	}
+	f.root.Content = append(f.root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// removes top level key
+func (f *File) remove(key string) {
+	for i := 0; i+1 < len(f.root.Content); i += 2 {
+		if f.root.Content[i].Value == key {
+			f.root.Content = append(f.root.Content[:i], f.root.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// returns value node of key in mapping node, nil if there is no such key
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
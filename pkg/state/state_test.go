@@ -0,0 +1,332 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sharedState = `kind: state
+# owned by other module
+azbi:
+  status: applied
+  size: 3
+awsbi:
+  status: initialized
+  schema_version: 2
+k8s:
+  status: initialized
+`
+
+func TestSetAWSBIPreservesOtherSections(t *testing.T) {
+	// given
+	f, err := Parse([]byte(sharedState))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	if err := f.StartApply("0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	err = f.Applied(Config{Name: "epiphany", InstanceCount: 2, Region: "eu-central-1"}, "0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	data, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"kind", "azbi", "awsbi", "k8s"}; !reflect.DeepEqual(f.Keys(), expected) {
+		t.Error("Expected keys ", expected, " got ", f.Keys())
+	}
+	if !strings.Contains(string(data), "# owned by other module\nazbi:\n  status: applied\n  size: 3\n") {
+		t.Error("Expected azbi section with comment to be preserved, got:\n", string(data))
+	}
+	var k8s struct{ Status string }
+	if ok, err := f.Section("k8s", &k8s); !ok || err != nil || k8s.Status != "initialized" {
+		t.Error("Expected k8s section to be preserved, got ", k8s, ok, err)
+	}
+	section, err := f.AWSBI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section.Status != Applied || section.Config == nil || section.Name != "epiphany" || section.InstanceCount != 2 {
+		t.Error("Expected applied awsbi section with config, got ", section)
+	}
+}
+
+func TestInitializeEmptyState(t *testing.T) {
+	// given
+	f, err := Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = func() time.Time { return time.Date(2020, 12, 1, 10, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	// when
+	if err := f.Initialize("0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	data, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `kind: state
+awsbi:
+  status: initialized
+  schema_version: 2
+  transitions:
+    - from: ""
+      to: initialized
+      at: 2020-12-01T10:00:00Z
+      version: 0.0.1
+`
+	if string(data) != expected {
+		t.Error("Expected:\n", expected, "\ngot:\n", string(data))
+	}
+}
+
+func TestInitializeKeepsConfig(t *testing.T) {
+	// given
+	f, err := Parse([]byte("kind: state\nawsbi:\n  status: destroyed\n  name: epiphany\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	if err := f.Initialize("0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	section, err := f.AWSBI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section.Status != Initialized || section.Config == nil || section.Name != "epiphany" {
+		t.Error("Expected initialized section with name kept, got ", section)
+	}
+}
+
+func TestDestroyedRemovesConfigAndOutput(t *testing.T) {
+	// given
+	f, err := Parse([]byte(sharedState))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.StartApply("0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Applied(Config{Name: "epiphany"}, "0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetOutput(&Output{VpcID: "vpc-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.StartDestroy("0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	if err := f.Destroyed("0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	section, err := f.AWSBI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section.Status != Destroyed || section.Config != nil || section.Output != nil {
+		t.Error("Expected only destroyed status, got ", section)
+	}
+	if len(section.Transitions) != 4 {
+		t.Error("Expected 4 transitions, got ", section.Transitions)
+	}
+}
+
+func TestParseTerraformOutput(t *testing.T) {
+	// given
+	data := `{
+  "private_ip": {"sensitive": false, "type": ["tuple", ["string"]], "value": ["10.1.1.1"]},
+  "public_ip": {"sensitive": false, "type": ["tuple", []], "value": []},
+  "vpc_id": {"sensitive": false, "type": "string", "value": "vpc-1"},
+  "private_subnet_ids": {"sensitive": false, "type": ["tuple", ["string"]], "value": ["subnet-1"]},
+  "public_subnet_ids": {"sensitive": false, "type": ["tuple", ["string"]], "value": ["subnet-2"]},
+  "private_route_table_id": {"sensitive": false, "type": "string", "value": "rtb-1"}
+}`
+
+	// when
+	output, err := ParseTerraformOutput([]byte(data))
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Output{
+		PrivateIP:           []string{"10.1.1.1"},
+		PublicIP:            []string{},
+		VpcID:               "vpc-1",
+		PublicSubnetIDs:     []string{"subnet-2"},
+		PrivateSubnetIDs:    []string{"subnet-1"},
+		PrivateRouteTableID: "rtb-1",
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Error("Expected ", expected, " got ", output)
+	}
+}
+
+func TestSaveIsAtomicAndKeepsPermissions(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.yml")
+	if err := ioutil.WriteFile(path, []byte(sharedState), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	if err := f.StartDestroy("0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	err = f.Save()
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "state.yml" {
+		t.Error("Expected only state file in directory, got ", files)
+	}
+	if files[0].Mode().Perm() != 0600 {
+		t.Error("Expected permissions ", os.FileMode(0600), " got ", files[0].Mode().Perm())
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	section, err := loaded.AWSBI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section.Status != Destroying {
+		t.Error("Expected status ", Destroying, " got ", section.Status)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	// when
+	f, err := Load(filepath.Join(os.TempDir(), "missing", "state.yml"))
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section, err := f.AWSBI(); section != nil || err != nil {
+		t.Error("Expected no awsbi section, got ", section, err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "awsbi-config.yml")
+	config := `kind: awsbi-config
+awsbi:
+  name: epiphany
+  instance_count: 1
+  region: eu-central-1
+  use_public_ip: true
+  nat_gateway_count: 1
+  subnets:
+    private:
+      count: 1
+    public:
+      count: 1
+  rsa_pub_path: "/shared/vms_rsa.pub"
+  os: ubuntu
+`
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	loaded, err := LoadConfig(path)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Config{
+		Name:            "epiphany",
+		InstanceCount:   1,
+		Region:          "eu-central-1",
+		UsePublicIP:     true,
+		NatGatewayCount: 1,
+		Subnets:         Subnets{Private: SubnetGroup{Count: 1}, Public: SubnetGroup{Count: 1}},
+		RsaPubPath:      "/shared/vms_rsa.pub",
+		OS:              "ubuntu",
+	}
+	if !reflect.DeepEqual(loaded, expected) {
+		t.Error("Expected ", expected, " got ", loaded)
+	}
+}
+
+func TestConfigValuesSkipsStatus(t *testing.T) {
+	// given
+	f, err := Parse([]byte(sharedState))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.StartApply("0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Applied(Config{Name: "epiphany"}, "0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetOutput(&Output{VpcID: "vpc-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	values, err := f.ConfigValues()
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range statusKeys {
+		if _, ok := values[key]; ok {
+			t.Error("Expected no ", key, " in module parameters")
+		}
+	}
+	if values["name"] != "epiphany" {
+		t.Error("Expected name epiphany, got ", values["name"])
+	}
+}
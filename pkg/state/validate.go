@@ -0,0 +1,145 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// statuses are all known values of module status
+var statuses = []Status{Initialized, Applying, Applied, Destroying, Destroyed, Failed}
+
+// Error is single problem found in state file. Line is 0 when problem is not related to any
+// position in file, e.g. missing section.
+type Error struct {
+	Line    int
+	Column  int
+	Key     string
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Key, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Key, e.Message)
+}
+
+// Errors are all problems found in state file
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Validate checks structure of state file: kind, sections of all modules and awsbi section,
+// returns Errors if state is invalid. Empty state is valid.
+func (f *File) Validate() error {
+	var errs Errors
+	if len(f.root.Content) == 0 {
+		return nil
+	}
+
+	if kind := f.value("kind"); kind == nil {
+		errs = append(errs, Error{Line: f.root.Line, Column: f.root.Column, Key: "kind", Message: "missing kind of file"})
+	} else if kind.Value != Kind {
+		errs = append(errs, Error{Line: kind.Line, Column: kind.Column, Key: "kind", Message: fmt.Sprintf("expected %q, got %q", Kind, kind.Value)})
+	}
+
+	for i := 0; i+1 < len(f.root.Content); i += 2 {
+		key, section := f.root.Content[i], f.root.Content[i+1]
+		if key.Value == "kind" {
+			continue
+		}
+		if section.Kind != yaml.MappingNode {
+			errs = append(errs, Error{Line: key.Line, Column: key.Column, Key: key.Value, Message: "module section has to be a mapping"})
+			continue
+		}
+		status := mappingValue(section, "status")
+		if status == nil {
+			errs = append(errs, Error{Line: key.Line, Column: key.Column, Key: key.Value, Message: "missing status of module"})
+			continue
+		}
+		if status.Kind != yaml.ScalarNode {
+			errs = append(errs, Error{Line: status.Line, Column: status.Column, Key: key.Value + ".status", Message: "status has to be a string"})
+			continue
+		}
+		if key.Value == ModuleKey && !knownStatus(Status(status.Value)) {
+			errs = append(errs, Error{Line: status.Line, Column: status.Column, Key: key.Value + ".status", Message: fmt.Sprintf("unknown status %q", status.Value)})
+		}
+	}
+
+	if len(errs) == 0 {
+		if section, err := f.AWSBI(); err != nil {
+			node := f.value(ModuleKey)
+			errs = append(errs, Error{Line: node.Line, Column: node.Column, Key: ModuleKey, Message: err.Error()})
+		} else if section != nil && section.SchemaVersion != SchemaVersion {
+			node := f.value(ModuleKey)
+			if version := mappingValue(node, "schema_version"); version != nil {
+				node = version
+			}
+			errs = append(errs, Error{Line: node.Line, Column: node.Column, Key: ModuleKey + ".schema_version",
+				Message: fmt.Sprintf("expected schema version %d, got %d, run init or plan to migrate the state", SchemaVersion, section.SchemaVersion)})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CheckDependencies checks that strong dependencies are present in state and applied and that weak
+// dependencies are applied when present, returns Errors if any dependency is not met
+func (f *File) CheckDependencies(strong, weak []string) error {
+	var errs Errors
+	for _, module := range strong {
+		if err := f.checkDependency(module, true); err != nil {
+			errs = append(errs, *err)
+		}
+	}
+	for _, module := range weak {
+		if err := f.checkDependency(module, false); err != nil {
+			errs = append(errs, *err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checks single dependency, returns nil if it is met
+func (f *File) checkDependency(module string, strong bool) *Error {
+	section := f.value(module)
+	if section == nil {
+		if strong {
+			return &Error{Key: module, Message: fmt.Sprintf("%s requires module %s, but it is missing in state", ModuleKey, module)}
+		}
+		return nil
+	}
+	status := mappingValue(section, "status")
+	if status == nil || Status(status.Value) != Applied {
+		value := ""
+		line, column := section.Line, section.Column
+		if status != nil {
+			value, line, column = status.Value, status.Line, status.Column
+		}
+		return &Error{Line: line, Column: column, Key: module, Message: fmt.Sprintf("%s requires module %s to be %s, but its status is %q", ModuleKey, module, Applied, value)}
+	}
+	return nil
+}
+
+func knownStatus(status Status) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package state
+
+import (
+	"testing"
+)
+
+func TestValidateSharedState(t *testing.T) {
+	// given
+	f, err := Parse([]byte(sharedState))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	err = f.Validate()
+
+	// then
+	if err != nil {
+		t.Error("Expected valid state, got ", err)
+	}
+}
+
+func TestValidateEmptyState(t *testing.T) {
+	// given
+	f, err := Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	err = f.Validate()
+
+	// then
+	if err != nil {
+		t.Error("Expected empty state to be valid, got ", err)
+	}
+}
+
+func TestValidateReportsPositions(t *testing.T) {
+	// given
+	f, err := Parse([]byte(`kind: config
+azbi: applied
+k8s:
+  size: 3
+awsbi:
+  status: unknown
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	err = f.Validate()
+
+	// then
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatal("Expected validation errors, got ", err)
+	}
+	expected := []Error{
+		{Line: 1, Column: 7, Key: "kind"},
+		{Line: 2, Column: 1, Key: "azbi"},
+		{Line: 3, Column: 1, Key: "k8s"},
+		{Line: 6, Column: 11, Key: "awsbi.status"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatal("Expected ", len(expected), " errors, got ", errs)
+	}
+	for i, e := range expected {
+		if errs[i].Line != e.Line || errs[i].Column != e.Column || errs[i].Key != e.Key {
+			t.Error("Expected error of ", e.Key, " at ", e.Line, ":", e.Column, " got ", errs[i])
+		}
+	}
+}
+
+func TestValidateSchemaVersion(t *testing.T) {
+	for _, section := range []string{"awsbi:\n  status: initialized\n", "awsbi:\n  status: initialized\n  schema_version: 1\n"} {
+		// given
+		f, err := Parse([]byte("kind: state\n" + section))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// when
+		err = f.Validate()
+
+		// then
+		errs, ok := err.(Errors)
+		if !ok || len(errs) != 1 || errs[0].Key != "awsbi.schema_version" {
+			t.Error("Expected error of schema version, got ", err)
+		}
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	// given
+	f, err := Parse([]byte(sharedState))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		strong []string
+		weak   []string
+		keys   []string
+	}{
+		{"applied strong", []string{"azbi"}, nil, nil},
+		{"missing strong", []string{"azure"}, nil, []string{"azure"}},
+		{"not applied strong", []string{"k8s"}, nil, []string{"k8s"}},
+		{"missing weak", nil, []string{"azure"}, nil},
+		{"not applied weak", nil, []string{"k8s"}, []string{"k8s"}},
+		{"mixed", []string{"azbi", "azure"}, []string{"k8s", "monitoring"}, []string{"azure", "k8s"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// when
+			err := f.CheckDependencies(test.strong, test.weak)
+
+			// then
+			if len(test.keys) == 0 {
+				if err != nil {
+					t.Error("Expected dependencies to be met, got ", err)
+				}
+				return
+			}
+			errs, ok := err.(Errors)
+			if !ok || len(errs) != len(test.keys) {
+				t.Fatal("Expected errors of ", test.keys, " got ", err)
+			}
+			for i, key := range test.keys {
+				if errs[i].Key != key {
+					t.Error("Expected error of ", key, " got ", errs[i])
+				}
+			}
+		})
+	}
+}
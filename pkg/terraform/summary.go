@@ -0,0 +1,65 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Summary is number of changed resources by resource type, e.g. aws_subnet
+type Summary map[string]Changes
+
+// Summarize counts changes of resources by their type
+func Summarize(resources []ResourceChange) Summary {
+	summary := make(Summary)
+	for _, resource := range resources {
+		changes := summary[resource.Type]
+		counted := Count([]ResourceChange{resource})
+		changes.Add += counted.Add
+		changes.Change += counted.Change
+		changes.Destroy += counted.Destroy
+		summary[resource.Type] = changes
+	}
+	return summary
+}
+
+// Total returns numbers of changes of all types
+func (s Summary) Total() Changes {
+	var total Changes
+	for _, changes := range s {
+		total.Add += changes.Add
+		total.Change += changes.Change
+		total.Destroy += changes.Destroy
+	}
+	return total
+}
+
+// Types returns resource types ordered by name
+func (s Summary) Types() []string {
+	types := make([]string, 0, len(s))
+	for resourceType := range s {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Text returns summary with line for each resource type, e.g. "2 aws_subnet to add"
+func (s Summary) Text() string {
+	var b strings.Builder
+	for _, resourceType := range s.Types() {
+		changes := s[resourceType]
+		var parts []string
+		for _, part := range []struct {
+			count  int
+			action string
+		}{{changes.Add, "to add"}, {changes.Change, "to change"}, {changes.Destroy, "to destroy"}} {
+			if part.count > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", part.count, part.action))
+			}
+		}
+		fmt.Fprintf(&b, "  %-28s %s\n", resourceType, strings.Join(parts, ", "))
+	}
+	fmt.Fprintf(&b, "Plan: %s.\n", s.Total())
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package terraform
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	// given
+	plan, err := ioutil.ReadFile("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := ResourceChanges(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	summary := Summarize(resources)
+
+	// then
+	expected := Summary{
+		"aws_key_pair": {Add: 1},
+		"aws_vpc":      {Change: 1},
+		"aws_instance": {Add: 1, Destroy: 1},
+		"aws_eip":      {Destroy: 1},
+	}
+	if len(summary) != len(expected) {
+		t.Error("Expected ", expected, " got ", summary)
+	}
+	for resourceType, changes := range expected {
+		if summary[resourceType] != changes {
+			t.Error("Expected ", resourceType, ": ", changes, " got ", summary[resourceType])
+		}
+	}
+	if summary.Total() != Count(resources) {
+		t.Error("Expected total ", Count(resources), " got ", summary.Total())
+	}
+}
+
+func TestSummaryText(t *testing.T) {
+	// given
+	summary := Summary{"aws_subnet": {Add: 2}, "aws_instance": {Add: 1, Destroy: 1}}
+
+	// when
+	text := summary.Text()
+
+	// then
+	expected := "  aws_instance                 1 to add, 1 to destroy\n" +
+		"  aws_subnet                   2 to add\n" +
+		"Plan: 3 to add, 0 to change, 1 to destroy.\n"
+	if text != expected {
+		t.Error("Expected:\n", expected, "got:\n", text)
+	}
+}
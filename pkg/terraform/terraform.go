@@ -0,0 +1,364 @@
+// Package terraform drives terraform binary for module lifecycle steps (plan, apply and destroy)
+// and returns typed results: numbers of added, changed and destroyed resources, outputs, duration
+// and errors. Results are read from machine readable terraform output (`terraform show -json` of
+// plan and `terraform output -json`), human readable output is only passed through.
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Operation is lifecycle step run by terraform
+type Operation string
+
+const (
+	Plan        Operation = "plan"
+	PlanDestroy Operation = "plan-destroy"
+	Apply       Operation = "apply"
+	Destroy     Operation = "destroy"
+)
+
+// Changes are numbers of resources changed by operation, counted the same way as in terraform
+// summary (replaced resource is both added and destroyed)
+type Changes struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+func (c Changes) String() string {
+	return fmt.Sprintf("%d to add, %d to change, %d to destroy", c.Add, c.Change, c.Destroy)
+}
+
+// ResourceChange is planned change of single resource
+type ResourceChange struct {
+	Address string   `json:"address"`
+	Type    string   `json:"type"`
+	Actions []string `json:"actions"`
+}
+
+// Result of operation
+type Result struct {
+	Operation Operation `json:"operation"`
+	Success   bool      `json:"success"`
+	Changes   Changes   `json:"changes"`
+	// Resources are planned changes of resources and Summary counts them by resource type
+	Resources []ResourceChange `json:"resources,omitempty"`
+	Summary   Summary          `json:"summary,omitempty"`
+	// Outputs are terraform outputs by name, set by apply
+	Outputs  map[string]interface{} `json:"outputs,omitempty"`
+	Started  time.Time              `json:"started"`
+	Duration Duration               `json:"duration"`
+	// Errors are error messages reported by terraform
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Duration is time.Duration written to JSON as text, e.g. "1m30s"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(text)
+	*d = Duration(parsed)
+	return err
+}
+
+// LoadResult reads result written as JSON
+func LoadResult(path string) (*Result, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read terraform result: %w", err)
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("cannot parse terraform result %s: %w", path, err)
+	}
+	return &result, nil
+}
+
+// Error is returned when terraform fails, Result describes the failed operation
+type Error struct {
+	Result *Result
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if len(e.Result.Errors) > 0 {
+		return fmt.Sprintf("terraform %s failed: %s", e.Result.Operation, strings.Join(e.Result.Errors, "; "))
+	}
+	return fmt.Sprintf("terraform %s failed: %v", e.Result.Operation, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Terraform runs terraform binary on module in Dir
+type Terraform struct {
+	// Binary is path of terraform binary, "terraform" from PATH when empty
+	Binary string
+	// Command runs terraform instead of Binary (e.g. docker run of image with terraform), arguments of terraform
+	// are appended to it. Command is run in current directory, it has to run terraform in Dir itself.
+	Command []string
+	// Dir is directory with terraform files of module
+	Dir string
+	// Env is added to environment of terraform, e.g. AWS credentials
+	Env []string
+	// Stdout and Stderr receive human readable output of terraform, it is discarded when nil
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// New returns terraform running terraform binary from PATH on module in dir
+func New(dir string) *Terraform {
+	return &Terraform{Dir: dir}
+}
+
+// PlanOptions are files used by plan
+type PlanOptions struct {
+	VarFile string
+	State   string
+	// Out is path of written plan
+	Out     string
+	Destroy bool
+}
+
+// Plan creates plan of changes and returns planned changes
+func (t *Terraform) Plan(ctx context.Context, options PlanOptions) (*Result, error) {
+	result := &Result{Operation: Plan, Started: now()}
+	args := []string{"plan", "-no-color", "-input=false",
+		"-var-file=" + options.VarFile, "-state=" + options.State, "-out=" + options.Out}
+	if options.Destroy {
+		result.Operation = PlanDestroy
+		args = append(args, "-destroy")
+	}
+	args = append(args, t.Dir)
+
+	if err := t.run(ctx, result, args...); err != nil {
+		return result, err
+	}
+	if err := t.readPlan(ctx, result, options.Out); err != nil {
+		return result, err
+	}
+	return t.finish(result), nil
+}
+
+// ApplyOptions are files used by apply
+type ApplyOptions struct {
+	State string
+	// Plan is path of plan created by Plan
+	Plan string
+	// Destroy is set when plan is plan of destruction
+	Destroy bool
+}
+
+// Apply applies plan and returns its changes and outputs read from state. Changes are read from the
+// plan, so result of failed apply holds planned changes, some of which may not have been applied.
+func (t *Terraform) Apply(ctx context.Context, options ApplyOptions) (*Result, error) {
+	result := &Result{Operation: Apply, Started: now()}
+	if options.Destroy {
+		result.Operation = Destroy
+	}
+	// plan is read first, as it cannot be used after it is applied
+	if err := t.readPlan(ctx, result, options.Plan); err != nil {
+		return result, err
+	}
+
+	if err := t.run(ctx, result, "apply", "-no-color", "-input=false", "-auto-approve",
+		"-state="+options.State, options.Plan); err != nil {
+		return result, err
+	}
+
+	if !options.Destroy {
+		outputs, err := t.Output(ctx, options.State)
+		if err != nil {
+			return result, err
+		}
+		result.Outputs = outputs
+	}
+	return t.finish(result), nil
+}
+
+// Output returns values of terraform outputs by name
+func (t *Terraform) Output(ctx context.Context, state string) (map[string]interface{}, error) {
+	data, err := t.capture(ctx, "output", "-no-color", "-json", "-state="+state)
+	if err != nil {
+		return nil, err
+	}
+	var outputs map[string]struct {
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("cannot parse terraform output: %w", err)
+	}
+	values := make(map[string]interface{}, len(outputs))
+	for name, output := range outputs {
+		values[name] = output.Value
+	}
+	return values, nil
+}
+
+// Show returns plan in JSON format of `terraform show -json`
+func (t *Terraform) Show(ctx context.Context, plan string) ([]byte, error) {
+	return t.capture(ctx, "show", "-no-color", "-json", plan)
+}
+
+// now returns current time, replaced in tests
+var now = time.Now
+
+// sets planned changes of result from plan file
+func (t *Terraform) readPlan(ctx context.Context, result *Result, plan string) error {
+	data, err := t.Show(ctx, plan)
+	if err != nil {
+		return t.fail(result, err, nil)
+	}
+	resources, err := ResourceChanges(data)
+	if err != nil {
+		return t.fail(result, err, nil)
+	}
+	result.Resources = resources
+	result.Summary = Summarize(resources)
+	result.Changes = Count(resources)
+	return nil
+}
+
+// ResourceChanges reads changes of resources from plan in JSON format of `terraform show -json`,
+// resources which do not change are left out
+func ResourceChanges(plan []byte) ([]ResourceChange, error) {
+	var document struct {
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Type    string `json:"type"`
+			Change  struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(plan, &document); err != nil {
+		return nil, fmt.Errorf("cannot parse terraform plan: %w", err)
+	}
+	var changes []ResourceChange
+	for _, resource := range document.ResourceChanges {
+		actions := resource.Change.Actions
+		if len(actions) == 1 && (actions[0] == "no-op" || actions[0] == "read") {
+			continue
+		}
+		changes = append(changes, ResourceChange{Address: resource.Address, Type: resource.Type, Actions: actions})
+	}
+	return changes, nil
+}
+
+// Count returns numbers of added, changed and destroyed resources
+func Count(resources []ResourceChange) Changes {
+	var changes Changes
+	for _, resource := range resources {
+		for _, action := range resource.Actions {
+			switch action {
+			case "create":
+				changes.Add++
+			case "update":
+				changes.Change++
+			case "delete":
+				changes.Destroy++
+			}
+		}
+	}
+	return changes
+}
+
+// runs terraform passing its output through
+func (t *Terraform) run(ctx context.Context, result *Result, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := t.command(ctx, args...)
+	cmd.Stdout = writer(t.Stdout)
+	cmd.Stderr = io.MultiWriter(&stderr, writer(t.Stderr))
+	if err := cmd.Run(); err != nil {
+		return t.fail(result, err, stderr.Bytes())
+	}
+	return nil
+}
+
+// runs terraform and returns its stdout without passing it through
+func (t *Terraform) capture(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := t.command(ctx, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = io.MultiWriter(&stderr, writer(t.Stderr))
+	if err := cmd.Run(); err != nil {
+		messages := errorMessages(stderr.Bytes())
+		if len(messages) == 0 {
+			return nil, fmt.Errorf("terraform %s failed: %w", args[0], err)
+		}
+		return nil, fmt.Errorf("terraform %s failed: %s", args[0], strings.Join(messages, "; "))
+	}
+	return stdout.Bytes(), nil
+}
+
+func (t *Terraform) command(ctx context.Context, args ...string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if len(t.Command) > 0 {
+		commandArgs := append(append([]string{}, t.Command[1:]...), args...)
+		cmd = exec.CommandContext(ctx, t.Command[0], commandArgs...)
+	} else {
+		binary := t.Binary
+		if binary == "" {
+			binary = "terraform"
+		}
+		cmd = exec.CommandContext(ctx, binary, args...)
+		cmd.Dir = t.Dir
+	}
+	cmd.Env = append(os.Environ(), t.Env...)
+	return cmd
+}
+
+// marks result as failed and returns Error
+func (t *Terraform) fail(result *Result, err error, stderr []byte) error {
+	t.finish(result)
+	result.Success = false
+	result.Errors = append(result.Errors, errorMessages(stderr)...)
+	if len(result.Errors) == 0 {
+		result.Errors = []string{err.Error()}
+	}
+	return &Error{Result: result, Err: err}
+}
+
+func (t *Terraform) finish(result *Result) *Result {
+	result.Success = true
+	result.Duration = Duration(now().Sub(result.Started))
+	return result
+}
+
+// returns summaries of errors reported by terraform ("Error: ..." lines)
+func errorMessages(stderr []byte) []string {
+	var messages []string
+	for _, line := range strings.Split(string(stderr), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Error: ") {
+			messages = append(messages, strings.TrimPrefix(line, "Error: "))
+		}
+	}
+	return messages
+}
+
+func writer(w io.Writer) io.Writer {
+	if w == nil {
+		return ioutil.Discard
+	}
+	return w
+}
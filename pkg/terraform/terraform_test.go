@@ -0,0 +1,161 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// returns terraform running fake binary from testdata, commands it was called with are written to
+// returned file
+func fake(t *testing.T, fail string) (*Terraform, string) {
+	binary, err := filepath.Abs("testdata/terraform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "terraform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := filepath.Join(dir, "calls")
+	return &Terraform{Binary: binary, Dir: dir, Env: []string{"CALLS=" + calls, "FAIL=" + fail}}, calls
+}
+
+// returns clock moving by second with every reading
+func clock() func() time.Time {
+	current := time.Date(2020, 11, 2, 10, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		current = current.Add(time.Second)
+		return current
+	}
+}
+
+func TestPlan(t *testing.T) {
+	// given
+	tf, calls := fake(t, "")
+	defer os.RemoveAll(tf.Dir)
+	var stdout bytes.Buffer
+	tf.Stdout = &stdout
+
+	// when
+	result, err := tf.Plan(context.Background(), PlanOptions{VarFile: "vars.tfvars.json", State: "terraform.tfstate", Out: "apply.tfplan"})
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || result.Operation != Plan || result.Changes != (Changes{Add: 2, Change: 1, Destroy: 2}) {
+		t.Error("Expected successful plan of 2 to add, 1 to change, 2 to destroy, got ", result)
+	}
+	if len(result.Resources) != 4 || result.Resources[2].Type != "aws_instance" {
+		t.Error("Expected 4 changed resources, got ", result.Resources)
+	}
+	if !strings.Contains(stdout.String(), "Plan: 2 to add") {
+		t.Error("Expected terraform output to be passed through, got ", stdout.String())
+	}
+	data, _ := ioutil.ReadFile(calls)
+	expected := "plan -no-color -input=false -var-file=vars.tfvars.json -state=terraform.tfstate -out=apply.tfplan " + tf.Dir + "\nshow -no-color -json apply.tfplan\n"
+	if string(data) != expected {
+		t.Error("Expected calls:\n", expected, "got:\n", string(data))
+	}
+}
+
+func TestApply(t *testing.T) {
+	// given
+	tf, _ := fake(t, "")
+	defer os.RemoveAll(tf.Dir)
+	now = clock()
+	defer func() { now = time.Now }()
+
+	// when
+	result, err := tf.Apply(context.Background(), ApplyOptions{State: "terraform.tfstate", Plan: "apply.tfplan"})
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Changes != (Changes{Add: 2, Change: 1, Destroy: 2}) {
+		t.Error("Expected changes of applied plan, got ", result.Changes)
+	}
+	if result.Outputs["vpc_id"] != "vpc-1" {
+		t.Error("Expected outputs, got ", result.Outputs)
+	}
+	if time.Duration(result.Duration) != time.Second {
+		t.Error("Expected duration of 1s, got ", time.Duration(result.Duration))
+	}
+}
+
+func TestPlanWithCommand(t *testing.T) {
+	// given
+	faked, calls := fake(t, "")
+	defer os.RemoveAll(faked.Dir)
+	tf := New("/resources/terraform")
+	tf.Command = []string{"sh", faked.Binary}
+	tf.Env = faked.Env
+
+	// when
+	result, err := tf.Plan(context.Background(), PlanOptions{VarFile: "vars.tfvars.json", State: "terraform.tfstate", Out: "apply.tfplan"})
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Changes != (Changes{Add: 2, Change: 1, Destroy: 2}) {
+		t.Error("Expected 2 to add, 1 to change, 2 to destroy, got ", result.Changes)
+	}
+	data, _ := ioutil.ReadFile(calls)
+	if !strings.HasPrefix(string(data), "plan -no-color -input=false -var-file=vars.tfvars.json -state=terraform.tfstate -out=apply.tfplan /resources/terraform\n") {
+		t.Error("Expected plan of module in /resources/terraform, got calls:\n", string(data))
+	}
+}
+
+func TestFailedApply(t *testing.T) {
+	// given
+	tf, _ := fake(t, "apply")
+	defer os.RemoveAll(tf.Dir)
+
+	// when
+	result, err := tf.Apply(context.Background(), ApplyOptions{State: "terraform.tfstate", Plan: "destroy.tfplan", Destroy: true})
+
+	// then
+	var terraformError *Error
+	if !errors.As(err, &terraformError) {
+		t.Fatal("Expected terraform error, got ", err)
+	}
+	if result.Success || result.Operation != Destroy || len(result.Errors) != 1 || result.Errors[0] != "apply failed on purpose" {
+		t.Error("Expected failed destroy with error of terraform, got ", result)
+	}
+	if err.Error() != "terraform destroy failed: apply failed on purpose" {
+		t.Error("Expected error message of terraform, got ", err)
+	}
+}
+
+func TestResultJSON(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "terraform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "result.json")
+	if err := ioutil.WriteFile(path, []byte(`{"operation": "plan", "success": true, "changes": {"add": 14, "change": 0, "destroy": 0}, "duration": "1m30s"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	result, err := LoadResult(path)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Changes.Add != 14 || time.Duration(result.Duration) != 90*time.Second {
+		t.Error("Expected 14 to add in 1m30s, got ", result)
+	}
+}
@@ -0,0 +1,69 @@
+// Package terraformtest computes resources terraform files of module create for config and
+// checks plans against them, so tests do not need numbers of resources counted by hand.
+package terraformtest
+
+import (
+	"testing"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/terraform"
+)
+
+// Resources returns number of resources of each type created for config, types without resources
+// are left out. It follows count of resources in resources/terraform.
+func Resources(config state.Config) map[string]int {
+	public, private := config.Subnets.Public.Count, config.Subnets.Private.Count
+	counts := map[string]int{
+		"time_static":                 1,
+		"aws_key_pair":                1,
+		"aws_resourcegroups_group":    1,
+		"aws_vpc":                     1,
+		"aws_security_group":          1,
+		"aws_internet_gateway":        1,
+		"aws_instance":                config.InstanceCount,
+		"aws_subnet":                  public + private,
+		"aws_route_table":             1 + config.NatGatewayCount,
+		"aws_route_table_association": public + private,
+		"aws_eip":                     config.NatGatewayCount,
+		"aws_nat_gateway":             config.NatGatewayCount,
+	}
+	for resourceType, count := range counts {
+		if count == 0 {
+			delete(counts, resourceType)
+		}
+	}
+	return counts
+}
+
+// Created returns summary of plan creating module for config from scratch
+func Created(config state.Config) terraform.Summary {
+	summary := make(terraform.Summary)
+	for resourceType, count := range Resources(config) {
+		summary[resourceType] = terraform.Changes{Add: count}
+	}
+	return summary
+}
+
+// Destroyed returns summary of plan destroying module created for config
+func Destroyed(config state.Config) terraform.Summary {
+	summary := make(terraform.Summary)
+	for resourceType, count := range Resources(config) {
+		summary[resourceType] = terraform.Changes{Destroy: count}
+	}
+	return summary
+}
+
+// AssertSummary reports every resource type with different changes than expected
+func AssertSummary(t testing.TB, expected, actual terraform.Summary) {
+	t.Helper()
+	for _, resourceType := range expected.Types() {
+		if actual[resourceType] != expected[resourceType] {
+			t.Error("Expected ", resourceType, ": ", expected[resourceType], " got ", actual[resourceType])
+		}
+	}
+	for _, resourceType := range actual.Types() {
+		if _, ok := expected[resourceType]; !ok {
+			t.Error("Expected no changes of ", resourceType, " got ", actual[resourceType])
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package terraformtest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+// defaults is config with defaults from resources/defaults.mk
+var defaults = state.Config{
+	Name:            "epiphany",
+	InstanceCount:   1,
+	Region:          "eu-central-1",
+	NatGatewayCount: 1,
+	Subnets:         state.Subnets{Private: state.SubnetGroup{Count: 1}, Public: state.SubnetGroup{Count: 1}},
+	OS:              "redhat",
+}
+
+func TestDefaultsCreate15Resources(t *testing.T) {
+	// when
+	total := Created(defaults).Total()
+
+	// then
+	if total.Add != 15 || total.Change != 0 || total.Destroy != 0 {
+		t.Error("Expected 15 resources to add, got ", total)
+	}
+}
+
+func TestResourcesCoverTerraformFiles(t *testing.T) {
+	// given
+	files, err := filepath.Glob("../../../resources/terraform/*.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules, err := filepath.Glob("../../../resources/terraform/modules/*/*.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource := regexp.MustCompile(`(?m)^resource "([a-z0-9_]+)"`)
+	declared := make(map[string]bool)
+	for _, file := range append(files, modules...) {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range resource.FindAllStringSubmatch(string(data), -1) {
+			declared[match[1]] = true
+		}
+	}
+
+	// when
+	counts := Resources(defaults)
+
+	// then
+	for resourceType := range declared {
+		if _, ok := counts[resourceType]; !ok {
+			t.Error("Expected count of ", resourceType, " declared in terraform files")
+		}
+	}
+	for resourceType := range counts {
+		if !declared[resourceType] {
+			t.Error("Expected ", resourceType, " to be declared in terraform files")
+		}
+	}
+}
+
+// AWS resource types of tagged terraform resources, empty for resources resource group does not select
+var groupTypes = map[string]string{
+	"aws_key_pair":             "",
+	"aws_resourcegroups_group": "",
+	"aws_vpc":                  "VPC",
+	"aws_security_group":       "SecurityGroup",
+	"aws_internet_gateway":     "InternetGateway",
+	"aws_instance":             "Instance",
+	"aws_subnet":               "Subnet",
+	"aws_route_table":          "RouteTable",
+	"aws_eip":                  "EIP",
+	"aws_nat_gateway":          "NatGateway",
+}
+
+func TestResourceGroupSelectsTaggedResources(t *testing.T) {
+	// given
+	files, err := filepath.Glob("../../../resources/terraform/modules/ec2/*.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := hclparse.NewParser()
+	selected := make(map[string]bool)
+	tagged := make(map[string]bool)
+	for _, file := range files {
+		parsed, diags := parser.ParseHCLFile(file)
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		content, _, _ := parsed.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "resource", LabelNames: []string{"type", "name"}}},
+		})
+		for _, block := range content.Blocks {
+			attributes, _, _ := block.Body.PartialContent(&hcl.BodySchema{Attributes: []hcl.AttributeSchema{{Name: "tags"}}})
+			if attributes.Attributes["tags"] != nil {
+				tagged[block.Labels[0]] = true
+			}
+		}
+		for _, match := range regexp.MustCompile(`"AWS::EC2::(\w+)"`).FindAllStringSubmatch(string(parsed.Bytes), -1) {
+			selected[match[1]] = true
+		}
+	}
+
+	// when
+	taggedTypes := make(map[string]bool)
+	for resourceType := range tagged {
+		groupType, ok := groupTypes[resourceType]
+		if !ok {
+			t.Error("Expected AWS type of tagged ", resourceType)
+		}
+		if groupType != "" {
+			taggedTypes[groupType] = true
+		}
+	}
+
+	// then
+	if !reflect.DeepEqual(selected, taggedTypes) {
+		t.Error("Expected resource group to select tagged types ", taggedTypes, " got ", selected)
+	}
+}
+
+func TestCountsFollowConfig(t *testing.T) {
+	// given
+	config := defaults
+	config.InstanceCount = 3
+	config.NatGatewayCount = 2
+	config.Subnets.Public.Count = 2
+	config.Subnets.Private.Count = 3
+
+	// when
+	counts := Resources(config)
+
+	// then
+	expected := map[string]int{"aws_instance": 3, "aws_subnet": 5, "aws_route_table": 3, "aws_route_table_association": 5, "aws_eip": 2, "aws_nat_gateway": 2}
+	for resourceType, count := range expected {
+		if counts[resourceType] != count {
+			t.Error("Expected ", count, " ", resourceType, " got ", counts[resourceType])
+		}
+	}
+}
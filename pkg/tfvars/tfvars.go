@@ -0,0 +1,162 @@
+// Package tfvars renders terraform variables file (vars.tfvars.json) from module parameters.
+// Variables are mapped onto the ones declared in terraform variables.tf, so parameter unknown to
+// terraform or variable terraform requires and module does not provide is found before terraform
+// is run.
+package tfvars
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+// Variable is terraform variable declared in variables.tf
+type Variable struct {
+	Name string
+	// Type is the type keyword (string, number, bool, object, ...), empty when type is not declared
+	Type string
+	// Optional is set when variable has default value
+	Optional bool
+}
+
+// LoadVariables reads variables declared in terraform file
+func LoadVariables(path string) ([]Variable, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read terraform variables: %w", err)
+	}
+	return parseVariables(data, path)
+}
+
+// ParseVariables reads variable blocks of terraform file. Only type and default attributes of blocks
+// are interpreted, which is all that is needed to map parameters onto variables.
+func ParseVariables(source string) ([]Variable, error) {
+	return parseVariables([]byte(source), "variables.tf")
+}
+
+// variables.tf as decoded by gohcl, anything besides variable blocks is left in Remain
+type variablesFile struct {
+	Variables []variableBlock `hcl:"variable,block"`
+	Remain    hcl.Body        `hcl:",remain"`
+}
+
+type variableBlock struct {
+	Name    string         `hcl:"name,label"`
+	Type    *hcl.Attribute `hcl:"type,optional"`
+	Default *hcl.Attribute `hcl:"default,optional"`
+	Remain  hcl.Body       `hcl:",remain"`
+}
+
+func parseVariables(data []byte, filename string) ([]Variable, error) {
+	file, diags := hclparse.NewParser().ParseHCL(data, filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	var decoded variablesFile
+	if diags := gohcl.DecodeBody(file.Body, nil, &decoded); diags.HasErrors() {
+		return nil, diags
+	}
+	variables := make([]Variable, 0, len(decoded.Variables))
+	for _, block := range decoded.Variables {
+		variable := Variable{Name: block.Name, Optional: block.Default != nil}
+		if block.Type != nil {
+			variable.Type = typeKeyword(block.Type.Expr)
+		}
+		variables = append(variables, variable)
+	}
+	return variables, nil
+}
+
+// returns keyword of type expression, e.g. string for string and map for map(string)
+func typeKeyword(expr hcl.Expression) string {
+	if keyword := hcl.ExprAsKeyword(expr); keyword != "" {
+		return keyword
+	}
+	if call, diags := hcl.ExprCall(expr); !diags.HasErrors() {
+		return call.Name
+	}
+	return ""
+}
+
+// Render returns content of tfvars file with module parameters, returns error when parameter is
+// not declared as variable, variable without default value is not set or value does not match
+// type of variable. Parameters which are not set and have default value are omitted.
+func Render(config state.Config, variables []Variable) ([]byte, error) {
+	values, err := toValues(config)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := make(map[string]Variable, len(variables))
+	var problems []string
+	for _, variable := range variables {
+		declared[variable.Name] = variable
+		value, ok := values[variable.Name]
+		if !ok {
+			if !variable.Optional {
+				problems = append(problems, fmt.Sprintf("variable %s is required by terraform, but module does not set it", variable.Name))
+			}
+			continue
+		}
+		if !matches(variable.Type, value) {
+			problems = append(problems, fmt.Sprintf("variable %s has type %s, but module sets %v", variable.Name, variable.Type, value))
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := declared[name]; !ok {
+			problems = append(problems, fmt.Sprintf("parameter %s is not declared as terraform variable", name))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("module parameters do not match terraform variables:\n%s", strings.Join(problems, "\n"))
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// returns parameters as values of variables, the same way as they are written to tfvars file
+func toValues(config state.Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// tells if JSON value can be assigned to variable of type
+func matches(variableType string, value interface{}) bool {
+	switch value.(type) {
+	case string:
+		return variableType == "string"
+	case float64:
+		return variableType == "number"
+	case bool:
+		return variableType == "bool"
+	case map[string]interface{}:
+		return variableType == "object" || variableType == "map"
+	case []interface{}:
+		return variableType == "list" || variableType == "set" || variableType == "tuple"
+	}
+	return variableType == "" || variableType == "any"
+}
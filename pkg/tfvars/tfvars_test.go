@@ -0,0 +1,127 @@
+package tfvars
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+)
+
+var config = state.Config{
+	Name:            "epiphany",
+	InstanceCount:   2,
+	Region:          "eu-central-1",
+	UsePublicIP:     true,
+	NatGatewayCount: 1,
+	Subnets:         state.Subnets{Private: state.SubnetGroup{Count: 1}, Public: state.SubnetGroup{Count: 2}},
+	RsaPubPath:      "/shared/vms_rsa.pub",
+	OS:              "ubuntu",
+}
+
+func TestRenderModuleVariables(t *testing.T) {
+	// given
+	variables, err := LoadVariables("../../resources/terraform/variables.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	data, err := Render(config, variables)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "instance_count": 2,
+  "name": "epiphany",
+  "nat_gateway_count": 1,
+  "os": "ubuntu",
+  "region": "eu-central-1",
+  "rsa_pub_path": "/shared/vms_rsa.pub",
+  "subnets": {
+    "private": {
+      "count": 1
+    },
+    "public": {
+      "count": 2
+    }
+  },
+  "use_public_ip": true
+}
+`
+	if string(data) != expected {
+		t.Error("Expected:\n", expected, "\ngot:\n", string(data))
+	}
+}
+
+func TestParseVariables(t *testing.T) {
+	// when
+	variables, err := ParseVariables(`
+# comment with variable "commented" {}
+variable "name" { type = string }
+/* variable "block" {
+} */
+variable "size" {
+  description = "Size { in GiB }"
+  type        = number // comment
+  default     = 64
+}
+variable "tags" {
+  type = map(string)
+  validation {
+    condition     = length(var.tags) > 0
+    error_message = "Tags are required."
+  }
+}
+`)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Variable{{"name", "string", false}, {"size", "number", true}, {"tags", "map", false}}
+	if len(variables) != len(expected) {
+		t.Fatal("Expected ", expected, " got ", variables)
+	}
+	for i, variable := range expected {
+		if variables[i] != variable {
+			t.Error("Expected ", variable, " got ", variables[i])
+		}
+	}
+}
+
+func TestRenderReportsMismatchedVariables(t *testing.T) {
+	// given
+	variables, err := LoadVariables("../../resources/terraform/variables.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changed []Variable
+	for _, variable := range variables {
+		switch variable.Name {
+		case "os":
+			continue
+		case "instance_count":
+			variable.Type = "string"
+		}
+		changed = append(changed, variable)
+	}
+	changed = append(changed, Variable{Name: "zone", Type: "string"}, Variable{Name: "tags", Type: "map", Optional: true})
+
+	// when
+	_, err = Render(config, changed)
+
+	// then
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	for _, problem := range []string{"variable instance_count has type string", "variable zone is required", "parameter os is not declared"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Error("Expected error to contain ", problem, " got ", err)
+		}
+	}
+	if strings.Contains(err.Error(), "tags") {
+		t.Error("Expected optional variable to be allowed, got ", err)
+	}
+}
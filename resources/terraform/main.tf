@@ -1,11 +1,21 @@
-resource "aws_key_pair" "kp" {
-  key_name_prefix   = "${var.name}-kp"
-  public_key = file(var.rsa_pub_path)
+# creation time of environment read by awsbi-reaper janitor, it is kept by later applies
+resource "time_static" "created_at" {}
+
+locals {
+  # tags of all resources, resource group of module selects resources by resource_group tag
   tags = {
     resource_group = var.name
+    created_at     = time_static.created_at.rfc3339
   }
 }
 
+# resource groups cannot select key pairs, reaper and janitor list them by tag on their own
+resource "aws_key_pair" "kp" {
+  key_name_prefix   = "${var.name}-kp"
+  public_key = file(var.rsa_pub_path)
+  tags       = local.tags
+}
+
 module "ec2" {
   source            = "./modules/ec2"
   name              = var.name
@@ -17,6 +27,7 @@ module "ec2" {
   region            = var.region
   key_name          = aws_key_pair.kp.key_name
   os                = var.os
+  tags              = local.tags
 
   providers = {
     aws = aws
@@ -33,12 +33,14 @@ resource "aws_resourcegroups_group" "rg" {
   "TagFilters": [
     {
       "Key": "resource_group",
-      "Values": ["${var.name}"]
+      "Values": ["${var.tags["resource_group"]}"]
     }
   ]
 }
 JSON
   }
+
+  tags = var.tags
 }
 
 resource "aws_instance" "awsbi" {
@@ -57,8 +59,7 @@ resource "aws_instance" "awsbi" {
     aws_security_group.awsbi_security_group.id
   ]
 
-  tags = {
+  tags = merge(var.tags, {
     Name = "${var.name}-instance${count.index}"
-    resource_group = var.name
-  }
+  })
 }
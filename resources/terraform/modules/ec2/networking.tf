@@ -8,10 +8,9 @@ resource "aws_vpc" "awsbi_vpc" {
   enable_dns_support    = "true"
   enable_dns_hostnames  = "true"
 
-  tags = {
-    Name           = "${var.name}-vpc"
-    resource_group = var.name
-  }
+  tags = merge(var.tags, {
+    Name = "${var.name}-vpc"
+  })
 }
 
 resource "aws_security_group" "awsbi_security_group" {
@@ -32,9 +31,7 @@ resource "aws_security_group" "awsbi_security_group" {
     cidr_blocks = ["0.0.0.0/0"]
   }
 
-  tags = {
-    resource_group = var.name
-  }
+  tags = var.tags
 }
 
 # --- Public ---
@@ -45,19 +42,17 @@ resource "aws_subnet" "awsbi_public_subnet" {
   cidr_block        = local.public_cidr_blocks[count.index]
   availability_zone = element(data.aws_availability_zones.available.names, count.index)
 
-  tags = {
-    Name           = "${var.name}-subnet-public${count.index}"
-    resource_group = var.name
-  }
+  tags = merge(var.tags, {
+    Name = "${var.name}-subnet-public${count.index}"
+  })
 }
 
 resource "aws_internet_gateway" "awsbi_internet_gateway" {
   vpc_id = aws_vpc.awsbi_vpc.id
 
-  tags = {
-    Name           = "${var.name}-ig"
-    resource_group = var.name
-  }
+  tags = merge(var.tags, {
+    Name = "${var.name}-ig"
+  })
 }
 
 resource "aws_route_table" "awsbi_route_table_public" {
@@ -68,10 +63,9 @@ resource "aws_route_table" "awsbi_route_table_public" {
     gateway_id = aws_internet_gateway.awsbi_internet_gateway.id
   }
 
-  tags = {
-    Name           = "${var.name}-rt-public"
-    resource_group = var.name
-  }
+  tags = merge(var.tags, {
+    Name = "${var.name}-rt-public"
+  })
 }
 
 resource "aws_route_table_association" "awsbi_route_association_public" {
@@ -88,20 +82,18 @@ resource "aws_subnet" "awsbi_private_subnet" {
   cidr_block        = local.private_cidr_blocks[count.index]
   availability_zone = element(data.aws_availability_zones.available.names, count.index)
 
-  tags = {
-    Name           = "${var.name}-subnet-private${count.index}"
-    resource_group = var.name
-  }
+  tags = merge(var.tags, {
+    Name = "${var.name}-subnet-private${count.index}"
+  })
 }
 
 resource "aws_eip" "awsbi_nat_gateway" {
   count = var.nat_gateway_count
   vpc   = true
 
-  tags = {
-    Name           = "${var.name}-eip${count.index}"
-    resource_group = var.name
-  }
+  tags = merge(var.tags, {
+    Name = "${var.name}-eip${count.index}"
+  })
 }
 
 resource "aws_nat_gateway" "awsbi_nat_gateway" {
@@ -109,10 +101,9 @@ resource "aws_nat_gateway" "awsbi_nat_gateway" {
   allocation_id = aws_eip.awsbi_nat_gateway[count.index].id
   subnet_id     = element(aws_subnet.awsbi_public_subnet.*.id, count.index)
 
-  tags = {
-    Name           = "${var.name}-ng${count.index}"
-    resource_group = var.name
-  }
+  tags = merge(var.tags, {
+    Name = "${var.name}-ng${count.index}"
+  })
 
   depends_on = [ aws_internet_gateway.awsbi_internet_gateway ]
 }
@@ -126,10 +117,9 @@ resource "aws_route_table" "awsbi_route_table_private" {
     nat_gateway_id = aws_nat_gateway.awsbi_nat_gateway[count.index].id
   }
 
-  tags = {
-    Name           = "${var.name}-rt-private${count.index}"
-    resource_group = var.name
-  }
+  tags = merge(var.tags, {
+    Name = "${var.name}-rt-private${count.index}"
+  })
 }
 
 resource "aws_route_table_association" "awsbi_route_association_private" {
@@ -61,6 +61,11 @@ variable "subnets" {
   }
 }
 
+variable "tags" {
+  description = "Tags of all resources, resource group selects resources by their resource_group tag"
+  type        = map(string)
+}
+
 variable "os" {
   description = "Operating System to launch"
   type = string
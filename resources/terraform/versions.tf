@@ -5,5 +5,9 @@ terraform {
       source = "hashicorp/aws"
       version = "3.7.0"
     }
+    time = {
+      source = "hashicorp/time"
+      version = "0.6.0"
+    }
   }
 }
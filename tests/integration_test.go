@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -12,18 +13,19 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
-	"regexp"
-	"strings"
 	"testing"
-	"time"
 
 	"golang.org/x/crypto/ssh"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/resourcegroups"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/reaper"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/state"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/terraform"
+	"github.com/epiphany-platform/aws-basic-infrastructure/pkg/terraform/terraformtest"
 )
 
 const (
@@ -32,7 +34,13 @@ const (
 	moduleName  = "bi-module"
 	awsRegion   = "eu-central-1"
 	sshKeyName  = "vms_rsa"
-	retries     = 30
+	// policy of tests allows SSH open to the whole internet opened by module
+	policyFileName = "policy.yml"
+	policyFile     = "M_POLICY_FILE=/shared/" + policyFileName
+	// paths of module files in container
+	moduleDir     = "/resources/terraform"
+	moduleFiles   = "/shared/awsbi"
+	terraformPlan = moduleFiles + "/test.tfplan"
 )
 
 var (
@@ -61,7 +69,7 @@ func TestMain(m *testing.M) {
 
 func TestOnInitWithDefaultsShouldCreateProperFileAndFolder(t *testing.T) {
 	// given
-	expectedFileContentRegexp := "kind: state\nawsbi:\n  status: initialized"
+	expectedStatus := state.Initialized
 
 	// when
 	_, stderr := runDocker(t, "init", "M_NAME="+moduleName)
@@ -70,92 +78,89 @@ func TestOnInitWithDefaultsShouldCreateProperFileAndFolder(t *testing.T) {
 		t.Fatal("There was an error during executing a command. ", string(stderr.Bytes()))
 	}
 
-	data, err := ioutil.ReadFile(stateFilePath)
+	stateFile, err := state.Load(stateFilePath)
 
 	if err != nil {
 		t.Fatal("Cannot read state file: ", stateFilePath, err)
 	}
 
-	fileContent := string(data)
-
-	matched, err := regexp.MatchString(expectedFileContentRegexp, fileContent)
+	section, err := stateFile.AWSBI()
 	if err != nil {
-		t.Fatal("There was an error matching expression: ", err)
+		t.Fatal("Cannot decode state file: ", err)
 	}
 
 	// then
-	if !matched {
-		t.Error("Expected to find expression matching:\n", expectedFileContentRegexp, "\nbut found:\n", fileContent)
+	if stateFile.Kind() != state.Kind {
+		t.Error("Expected kind ", state.Kind, " got ", stateFile.Kind())
+	}
+	if section == nil || section.Status != expectedStatus {
+		t.Error("Expected awsbi section with status ", expectedStatus, " got ", section)
 	}
 
 }
 
 func TestOnPlanWithDefaultsShouldDisplayPlan(t *testing.T) {
 	// given
-	expectedOutputRegexp := ".*Plan: 14 to add, 0 to change, 0 to destroy.*"
+	expectedSummary := terraformtest.Created(loadConfig(t))
+	expectedChanges := expectedSummary.Total()
 
 	// when
-	stdout, stderr := runDocker(t, "plan", awsAccessKey, awsSecretKey)
+	_, stderr := runDocker(t, "plan", awsAccessKey, awsSecretKey, policyFile)
 
 	if stderr.Len() > 0 {
 		t.Fatal("There was an error during executing a command. ", string(stderr.Bytes()))
 	}
 
-	outStr := string(stdout.Bytes())
-
-	matched, err := regexp.MatchString(expectedOutputRegexp, outStr)
-	if err != nil {
-		t.Fatal("There was an error matching expression: ", err)
-	}
+	result := planWithTerraform(t, false)
 
 	// then
-	if !matched {
-		t.Error("Expected to find expression matching:\n", expectedOutputRegexp, "\nbut found:\n", outStr)
+	if !result.Success || result.Changes != expectedChanges {
+		t.Error("Expected ", expectedChanges, " got ", result.Changes, " with errors ", result.Errors)
 	}
-
+	terraformtest.AssertSummary(t, expectedSummary, result.Summary)
 }
 
 func TestOnApplyShouldCreateEnvironment(t *testing.T) {
 	// given
-	expectedOutputRegexp := ".*Apply complete! Resources: 14 added, 0 changed, 0 destroyed.*"
+	expectedChanges := terraform.Changes{}
 
 	// when
-	stdout, stderr := runDocker(t, "apply", awsAccessKey, awsSecretKey)
+	_, stderr := runDocker(t, "apply", awsAccessKey, awsSecretKey, policyFile)
 
 	if stderr.Len() > 0 {
 		t.Fatal("There was an error during executing a command. ", string(stderr.Bytes()))
 	}
 
-	outStr := string(stdout.Bytes())
-
-	matched, err := regexp.MatchString(expectedOutputRegexp, outStr)
+	// environment matches config when there is nothing left to plan
+	result := planWithTerraform(t, false)
+	outputs, err := newTerraform().Output(context.Background(), moduleFiles+"/terraform.tfstate")
 	if err != nil {
-		t.Fatal("There was an error matching expression: ", err)
+		t.Fatal("Cannot read terraform outputs: ", err)
 	}
 
 	// then
-	if !matched {
-		t.Error("Expected to find expression matching:\n", expectedOutputRegexp, "\nbut found:\n", outStr)
+	if !result.Success || result.Changes != expectedChanges {
+		t.Error("Expected ", expectedChanges, " got ", result.Changes, " with errors ", result.Errors)
+	}
+	if outputs["vpc_id"] == nil {
+		t.Error("Expected vpc_id output, got ", outputs)
 	}
 
-	checkNumberOfVms(t)
+	checkNumberOfVms(t, newEc2Client(t), loadConfig(t).InstanceCount)
 }
 
-// checks if the proper number of ec2s has been created
-func checkNumberOfVms(t *testing.T) {
-	// given
-	instancesNumber := 1
-
+// creates ec2 client for region used by tests
+func newEc2Client(t *testing.T) ec2iface.EC2API {
 	newSession, errSession := session.NewSession(&aws.Config{Region: aws.String(awsRegion)})
 	if errSession != nil {
 		t.Fatal("Cannot get session.", errSession)
 	}
+	return ec2.New(newSession)
+}
 
+// checks if the proper number of ec2s has been created
+func checkNumberOfVms(t *testing.T, ec2Client ec2iface.EC2API, instancesNumber int) {
 	// when
-
-	// ec2
-	ec2Client := ec2.New(newSession)
-
 	ec2DescInp := &ec2.DescribeInstancesInput{
 		Filters: []*ec2.Filter{
 			{
@@ -164,6 +169,14 @@ func checkNumberOfVms(t *testing.T) {
 					aws.String(awsTagValue),
 				},
 			},
+			// instances terminated by previous runs stay visible for about an hour
+			{
+				Name: aws.String("instance-state-name"),
+				Values: []*string{
+					aws.String(ec2.InstanceStateNamePending),
+					aws.String(ec2.InstanceStateNameRunning),
+				},
+			},
 		},
 	}
 
@@ -172,59 +185,93 @@ func checkNumberOfVms(t *testing.T) {
 		t.Fatal("There was an error. ", err)
 	}
 
+	instances := 0
+	for _, reservation := range ec2Result.Reservations {
+		instances += len(reservation.Instances)
+	}
+
 	// then
-	if len(ec2Result.Reservations[0].Instances) != 1 {
-		t.Error("Expected ", instancesNumber, "instance, got ", len(ec2Result.Reservations[0].Instances))
+	if instances != instancesNumber {
+		t.Error("Expected ", instancesNumber, "instance, got ", instances)
 	}
 
 }
 
 func TestOnDestroyPlanShouldDisplayDestroyPlan(t *testing.T) {
 	// given
-	expectedOutputRegexp := "Plan: 0 to add, 0 to change, 14 to destroy"
+	expectedSummary := terraformtest.Destroyed(loadConfig(t))
+	expectedChanges := expectedSummary.Total()
 
 	// when
-	stdout, stderr := runDocker(t, "plan-destroy", awsAccessKey, awsSecretKey)
+	_, stderr := runDocker(t, "plan-destroy", awsAccessKey, awsSecretKey)
 
 	if stderr.Len() > 0 {
 		t.Fatal("There was an error during executing a command. ", string(stderr.Bytes()))
 	}
 
-	outStr := string(stdout.Bytes())
-
-	matched, err := regexp.MatchString(expectedOutputRegexp, outStr)
-	if err != nil {
-		t.Fatal("There was an error matching expression: ", err)
-	}
+	result := planWithTerraform(t, true)
 
 	// then
-	if !matched {
-		t.Error("Expected to find expression matching:\n", expectedOutputRegexp, "\nbut found:\n", outStr)
+	if !result.Success || result.Changes != expectedChanges {
+		t.Error("Expected ", expectedChanges, " got ", result.Changes, " with errors ", result.Errors)
 	}
+	terraformtest.AssertSummary(t, expectedSummary, result.Summary)
 }
 
 func TestOnDestroyShouldDestroyEnvironment(t *testing.T) {
 	// given
-	expectedOutputRegexp := "Apply complete! Resources: 0 added, 0 changed, 14 destroyed."
+	expectedSummary := terraformtest.Created(loadConfig(t))
+	expectedChanges := expectedSummary.Total()
 
 	// when
-	stdout, stderr := runDocker(t, "destroy", awsAccessKey, awsSecretKey)
+	_, stderr := runDocker(t, "destroy", awsAccessKey, awsSecretKey)
 
 	if stderr.Len() > 0 {
 		t.Fatal("There was an error during executing a command. ", string(stderr.Bytes()))
 	}
 
-	outStr := string(stdout.Bytes())
+	// environment is destroyed when all resources would be created again
+	result := planWithTerraform(t, false)
 
-	matched, err := regexp.MatchString(expectedOutputRegexp, outStr)
+	// then
+	if !result.Success || result.Changes != expectedChanges {
+		t.Error("Expected ", expectedChanges, " got ", result.Changes, " with errors ", result.Errors)
+	}
+	terraformtest.AssertSummary(t, expectedSummary, result.Summary)
+}
+
+// returns terraform of module image run with docker, shared directory is mounted like for module commands
+// and AWS credentials are passed from environment of tests
+func newTerraform() *terraform.Terraform {
+	tf := terraform.New(moduleDir)
+	tf.Command = []string{dockerExecPath, "run", "--rm", "-v", mountDir, "-w", moduleDir,
+		"-e", "AWS_ACCESS_KEY_ID", "-e", "AWS_SECRET_ACCESS_KEY", "--entrypoint", "terraform", imageTag}
+	tf.Stdout, tf.Stderr = os.Stdout, os.Stderr
+	return tf
+}
+
+// plans changes of environment with variables and state written by module, plan is written to separate file,
+// so plans of module are kept
+func planWithTerraform(t *testing.T, destroy bool) *terraform.Result {
+	result, err := newTerraform().Plan(context.Background(), terraform.PlanOptions{
+		VarFile: moduleFiles + "/vars.tfvars.json",
+		State:   moduleFiles + "/terraform.tfstate",
+		Out:     terraformPlan,
+		Destroy: destroy,
+	})
 	if err != nil {
-		t.Fatal("There was an error matching expression: ", err)
+		t.Fatal("Cannot plan with terraform: ", err)
 	}
+	return result
+}
 
-	// then
-	if !matched {
-		t.Error("Expected to find expression matching:\n", expectedOutputRegexp, "\nbut found:\n", outStr)
+// reads module parameters from config file written by init
+func loadConfig(t *testing.T) state.Config {
+	config, err := state.LoadConfig(filepath.Join(sharedAbsoluteFilePath, "awsbi", "awsbi-config.yml"))
+	if err != nil {
+		t.Fatal("Cannot read config file: ", err)
 	}
+	return *config
 }
 
 // initializes test with creation of key pair and checks if variables need to run tests are setup
@@ -260,6 +307,10 @@ func setup() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	err = ioutil.WriteFile(filepath.Join(sharedAbsoluteFilePath, policyFileName), []byte("rules:\n  world-open-ingress: warning\n"), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
 	log.Println("Generating Keys")
 	err = generateRsaKeyPair(sharedAbsoluteFilePath, sshKeyName)
 	if err != nil {
@@ -286,77 +337,24 @@ func cleanupDiskTestStructure() {
 // cleans up AWS resources if module couldn't clean up resources properly during the test
 func cleanupAWSResources() {
 
-	newSession, errSession := session.NewSession(&aws.Config{Region: aws.String(awsRegion)})
-	if errSession != nil {
-		log.Fatal("Cannot get session.", errSession)
-	}
-
-	rgClient := resourcegroups.New(newSession)
-
-	rgName := moduleName + "-rg"
-	kpName := moduleName + "-kp"
-
-	rgResourcesList, errResourcesList := rgClient.ListGroupResources(&resourcegroups.ListGroupResourcesInput{
-		GroupName: aws.String(rgName),
+	r, err := reaper.New(reaper.Config{
+		GroupName: moduleName + "-rg",
+		TagKey:    awsTagName,
+		TagValue:  awsTagValue,
+		Region:    awsRegion,
 	})
-
-	if errResourcesList != nil {
-		if aerr, ok := errResourcesList.(awserr.Error); ok {
-			log.Println(aerr.Code())
-			if aerr.Code() == "NotFoundException" {
-				log.Println("Resource group: ", rgName, " not found.")
-			} else {
-				log.Fatal("Resource group: Cannot get list of resources: ", errResourcesList)
-			}
-		} else {
-			log.Fatal("Resource group: There was an error: ", errResourcesList.Error())
-		}
+	if err != nil {
+		log.Fatal("Cannot create reaper.", err)
 	}
 
-	resourcesTypesToRemove := []string{"Instance", "SecurityGroup", "NatGateway", "EIP", "InternetGateway", "Subnet", "RouteTable", "VPC"}
-
-	for _, resourcesTypeToRemove := range resourcesTypesToRemove {
-
-		filtered := make([]*resourcegroups.ResourceIdentifier, 0)
-		for _, element := range rgResourcesList.ResourceIdentifiers {
-			s := strings.Split(*element.ResourceType, ":")
-			if s[4] == resourcesTypeToRemove {
-				filtered = append(filtered, element)
-			}
-
-		}
-
-		switch resourcesTypeToRemove {
-		case "Instance":
-			log.Println("Instance.")
-			removeEc2s(newSession, filtered)
-		case "EIP":
-			log.Println("Releasing public EIPs.")
-			releaseAddresses(newSession)
-		case "RouteTable":
-			log.Println("RouteTable.")
-			removeRouteTables(newSession, filtered)
-		case "InternetGateway":
-			log.Println("InternetGateway.")
-			removeInternetGateway(newSession, filtered)
-		case "SecurityGroup":
-			log.Println("SecurityGroup.")
-			removeSecurityGroup(newSession, filtered)
-		case "NatGateway":
-			log.Println("NatGateway.")
-			removeNatGateways(newSession, filtered)
-		case "Subnet":
-			log.Println("Subnet.")
-			removeSubnet(newSession, filtered)
-		case "VPC":
-			log.Println("VPC.")
-			removeVpc(newSession, filtered)
-		}
+	report, err := r.Run()
+	if err != nil {
+		log.Fatal("Cannot cleanup AWS resources: ", err)
+	}
+	if report.Failed() {
+		report.Print(os.Stderr)
+		log.Fatal("Cannot cleanup AWS resources.")
 	}
-
-	removeResourceGroup(newSession, rgName)
-
-	removeKeyPair(newSession, kpName)
 
 }
 
@@ -406,384 +404,3 @@ func generateRsaKeyPair(directory, name string) error {
 	}
 	return ioutil.WriteFile(path.Join(directory, name+".pub"), publicKeyBytes, 0644)
 }
-
-// removes ec2s using AWS session based on resource identifiers that belong to resource group
-func removeEc2s(session *session.Session, ec2sToRemove []*resourcegroups.ResourceIdentifier) {
-
-	ec2Client := ec2.New(session)
-
-	for _, ec2ToRemove := range ec2sToRemove {
-
-		ec2ToRemoveID := strings.Split(*ec2ToRemove.ResourceArn, "/")[1]
-		log.Println("EC2: Removing instance with ID: ", ec2ToRemoveID)
-
-		ec2DescInp := &ec2.DescribeInstancesInput{
-			InstanceIds: []*string{&ec2ToRemoveID},
-		}
-
-		outDesc, errDesc := ec2Client.DescribeInstances(ec2DescInp)
-		if errDesc != nil {
-			log.Fatalf("EC2: Describe error: %s", errDesc)
-		}
-		log.Printf("EC2: Describe output: %s", outDesc)
-
-		if outDesc.Reservations != nil {
-
-			instancesToTerminateInp := &ec2.TerminateInstancesInput{
-				InstanceIds: []*string{&ec2ToRemoveID},
-			}
-
-			outputTerm, errTerm := ec2Client.TerminateInstances(instancesToTerminateInp)
-			if errTerm != nil {
-				log.Fatalf("EC2: Terminate error: %s", outputTerm)
-			}
-			log.Printf("EC2: Terminate output: %s", outputTerm)
-
-			errWait := ec2Client.WaitUntilInstanceTerminated(ec2DescInp)
-			if errWait != nil {
-				log.Fatalf("EC2: Waiting for termination error: %s", errWait)
-			}
-		}
-
-	}
-
-}
-
-// removes route tables using AWS session based on resource identifiers that belong to resource group
-func removeRouteTables(session *session.Session, rtsToRemove []*resourcegroups.ResourceIdentifier) {
-
-	ec2Client := ec2.New(session)
-
-	for _, rtToRemove := range rtsToRemove {
-		rtIDToRemove := strings.Split(*rtToRemove.ResourceArn, "/")[1]
-		log.Println("RouteTable: rtIDToRemove: ", rtIDToRemove)
-
-		rtToDeleteInp := &ec2.DeleteRouteTableInput{
-			RouteTableId: &rtIDToRemove,
-		}
-
-		output, err := ec2Client.DeleteRouteTable(rtToDeleteInp)
-
-		if err != nil {
-			log.Fatal("RouteTable: Deleting route table error: ", err)
-		}
-
-		log.Println("RouteTable: Deleting route table: ", output)
-	}
-
-}
-
-// removes security groups using AWS session based on resource identifiers that belong to resource group
-func removeSecurityGroup(session *session.Session, sgsToRemove []*resourcegroups.ResourceIdentifier) {
-
-	ec2Client := ec2.New(session)
-
-	for _, sgToRemove := range sgsToRemove {
-		sgIDToRemove := strings.Split(*sgToRemove.ResourceArn, "/")[1]
-		log.Println("Security Group: sgIdToRemove: ", sgIDToRemove)
-
-		secGrpInp := &ec2.DeleteSecurityGroupInput{GroupId: &sgIDToRemove}
-
-		output, err := ec2Client.DeleteSecurityGroup(secGrpInp)
-		if err != nil {
-			log.Fatal("Security Group: Deleting security group error: ", err)
-		}
-
-		log.Println("Security Group: Deleting security group: ", output)
-	}
-
-}
-
-// removes internet gateways using AWS session based on resource identifiers that belong to resource group
-func removeInternetGateway(session *session.Session, igsToRemove []*resourcegroups.ResourceIdentifier) {
-
-	ec2Client := ec2.New(session)
-
-	for _, igToRemove := range igsToRemove {
-		igIDToRemove := strings.Split(*igToRemove.ResourceArn, "/")[1]
-		log.Println("Internet Gateway: igIdToRemove: ", igIDToRemove)
-
-		igDescribeInp := &ec2.DescribeInternetGatewaysInput{
-			InternetGatewayIds: []*string{&igIDToRemove},
-		}
-
-		descOut, descErr := ec2Client.DescribeInternetGateways(igDescribeInp)
-
-		if descErr != nil {
-			log.Fatal("Internet Gateway: Describing internet gateway error: ", descErr)
-		}
-		log.Println("Internet Gateway: Describing internet gateway: ", descOut)
-		vpcID := *descOut.InternetGateways[0].Attachments[0].VpcId
-
-		igDetachInp := &ec2.DetachInternetGatewayInput{
-			InternetGatewayId: &igIDToRemove,
-			VpcId:             &vpcID,
-		}
-
-		detachOut, detachErr := ec2Client.DetachInternetGateway(igDetachInp)
-		if detachErr != nil {
-			log.Fatal("Internet Gateway: Detaching internet gateway error: ", detachErr)
-		}
-		log.Println("Internet Gateway: Detaching internet gateway: ", detachOut)
-
-		igDeleteInp := &ec2.DeleteInternetGatewayInput{
-			InternetGatewayId: &igIDToRemove,
-		}
-
-		delOut, delErr := ec2Client.DeleteInternetGateway(igDeleteInp)
-		if delErr != nil {
-			log.Fatal("Internet Gateway: Deleting internet gateway error: ", delErr)
-		}
-		log.Println("Internet Gateway: Deleting internet gateway: ", delOut)
-	}
-}
-
-// removes nat gateways using AWS session based on resource identifiers that belong to resource group
-func removeNatGateways(session *session.Session, ngsToRemove []*resourcegroups.ResourceIdentifier) {
-
-	ec2Client := ec2.New(session)
-
-	for _, ngToRemove := range ngsToRemove {
-		ngIDToRemove := strings.Split(*ngToRemove.ResourceArn, "/")[1]
-		log.Println("Nat Gateway: ngIdToRemove: ", ngIDToRemove)
-		removeSingleNatGatewayWithRetries(ec2Client, ngIDToRemove)
-	}
-
-}
-
-// remove single nat gateway based on nat gateway ID using ec2 client
-func removeSingleNatGatewayWithRetries(ec2Client *ec2.EC2, ngIDToRemove string) {
-
-	found := true
-
-	for retry := 0; retry <= retries && found; retry++ {
-
-		found = describeNatGateway(ec2Client, ngIDToRemove)
-
-		if found == false {
-			continue
-		}
-
-		found = removeNatGateway(ec2Client, ngIDToRemove)
-
-		if found == false {
-			continue
-		}
-
-		waitForNatGatewayDelete(ec2Client, ngIDToRemove)
-
-		log.Println("Nat Gateway: Deleting NAT Gateway. ", ngIDToRemove, " Retry: ", retry)
-		time.Sleep(5 * time.Second)
-	}
-}
-
-// describe Nat Gateway based on ec2 client and Nat Gateway id, returns false if Nat Gateway not found or deleted
-func describeNatGateway(ec2Client *ec2.EC2, ngIDToDescribe string) bool {
-	descInp := &ec2.DescribeNatGatewaysInput{
-		NatGatewayIds: []*string{&ngIDToDescribe},
-	}
-
-	outDesc, errDesc := ec2Client.DescribeNatGateways(descInp)
-	if errDesc != nil {
-		log.Println(errDesc)
-		if aerr, ok := errDesc.(awserr.Error); ok {
-			if aerr.Code() == "NatGatewayNotFound" {
-				log.Println("Nat Gateway: Nat Gateway not found.")
-				return false
-			} else {
-				log.Fatal("Nat Gateway: Describe error: ", errDesc)
-			}
-		} else {
-			log.Fatal("Nat Gateway: There was an error: ", errDesc.Error())
-		}
-	}
-	log.Printf("Nat Gateway: Describe output: %s", outDesc)
-
-	if outDesc.NatGateways == nil || *outDesc.NatGateways[0].State == "deleted" {
-		log.Print("Nat Gateway: Element not found or has been already deleted.")
-		return false
-	}
-	return true
-}
-
-// appropriate Nat Gateway delete method based on ec2 client and Nat Gateway id, returns false if Nat Gateway not found
-func removeNatGateway(ec2Client *ec2.EC2, ngIDToRemove string) bool {
-	ngDelInp := &ec2.DeleteNatGatewayInput{
-		NatGatewayId: &ngIDToRemove,
-	}
-
-	_, err := ec2Client.DeleteNatGateway(ngDelInp)
-
-	if err != nil {
-		log.Println("Nat Gateway: Error: ", err)
-		if aerr, ok := err.(awserr.Error); ok {
-			if aerr.Code() == "NatGatewayNotFound" {
-				log.Print("Nat Gateway: Element not found.", err)
-				return false
-			}
-			if aerr.Code() != "ResourceNotReady" {
-				log.Fatal("Nat Gateway: Deleting NAT Gateway: ", err)
-			}
-		} else {
-			log.Fatal("Nat Gateway: Deleting NAT Gateway: ", err.Error())
-		}
-
-	}
-	return true
-}
-
-// wait for Nat Gateway to be removed based on ec2 client and Nat Gateway id
-func waitForNatGatewayDelete(ec2Client *ec2.EC2, ngIDToWait string) {
-	descInp := &ec2.DescribeNatGatewaysInput{
-		NatGatewayIds: []*string{&ngIDToWait},
-	}
-
-	errWait := ec2Client.WaitUntilNatGatewayAvailable(descInp)
-	if errWait != nil {
-		if aerr, ok := errWait.(awserr.Error); ok {
-			if aerr.Code() != "ResourceNotReady" {
-				log.Fatal("Nat Gateway: Wait error: ", errWait)
-			}
-		} else {
-			log.Fatal("Nat Gateway: There was an error: ", errWait.Error())
-		}
-	}
-}
-
-// removes subnets using AWS session based on resource identifiers that belong to resource group
-func removeSubnet(session *session.Session, subnetsToRemove []*resourcegroups.ResourceIdentifier) {
-
-	ec2Client := ec2.New(session)
-
-	for _, subnetToRemove := range subnetsToRemove {
-		subnetIDToRemove := strings.Split(*subnetToRemove.ResourceArn, "/")[1]
-		log.Println("Subnet: subnetIdToRemove: ", subnetIDToRemove)
-
-		subnetInp := &ec2.DeleteSubnetInput{
-			SubnetId: &subnetIDToRemove,
-		}
-
-		output, err := ec2Client.DeleteSubnet(subnetInp)
-		if err != nil {
-			log.Fatal("Subnet: Deleting subnet error: ", err)
-		}
-		log.Println("Subnet: Deleting subnet: ", output)
-	}
-
-}
-
-// removes vpcs using AWS session based on resource identifiers that belong to resource group
-func removeVpc(session *session.Session, vpcsToRemove []*resourcegroups.ResourceIdentifier) {
-
-	ec2Client := ec2.New(session)
-
-	for _, vpcToRemove := range vpcsToRemove {
-		vpcIDToRemove := strings.Split(*vpcToRemove.ResourceArn, "/")[1]
-		log.Println("VPC: vpcIdToRemove: ", vpcIDToRemove)
-
-		vpcToDeleteInp := &ec2.DeleteVpcInput{
-			VpcId: &vpcIDToRemove,
-		}
-
-		output, err := ec2Client.DeleteVpc(vpcToDeleteInp)
-		if err != nil {
-			log.Println("VPC: Delete VPC error: ", err)
-		}
-		log.Println("VPC: Delete VPC: ", output)
-	}
-}
-
-// removes key pairs using AWS session based on resource identifiers that belong to resource group
-func removeKeyPair(session *session.Session, kpName string) {
-
-	ec2Client := ec2.New(session)
-
-	removeKeyInp := &ec2.DeleteKeyPairInput{
-		KeyName: &kpName,
-	}
-
-	output, err := ec2Client.DeleteKeyPair(removeKeyInp)
-	if err != nil {
-		log.Fatal("Key Pair: Deleting key pair error: ", err)
-	}
-	log.Println("Key Pair: Deleting key pair: ", output)
-}
-
-// releases elastic IPs using AWS session based on resource tag
-func releaseAddresses(session *session.Session) {
-
-    ec2Client := ec2.New(session)
-
-    eipDescInp := &ec2.DescribeAddressesInput {
-        Filters: []*ec2.Filter{
-            {
-                Name: aws.String("tag:" + awsTagName),
-                Values: []*string{
-                    aws.String(awsTagValue),
-                },
-            },
-        },
-    }
-
-    describeEips, err := ec2Client.DescribeAddresses(eipDescInp)
-    if err != nil {
-        log.Fatal("EIP: Cannot get EIP list.", err)
-    }
-
-    for _, eip := range describeEips.Addresses {
-
-        log.Printf("EIP: Releasing EIP with AllocationId: %s", *eip.AllocationId)
-
-        eipToReleaseInp := &ec2.ReleaseAddressInput{
-            AllocationId: eip.AllocationId,
-        }
-
-        found := true
-        for retry := 0; retry <= retries && found; retry++ {
-            _, err := ec2Client.ReleaseAddress(eipToReleaseInp)
-            if err != nil {
-                if aerr, ok := err.(awserr.Error); ok {
-                    if aerr.Code() == "InvalidAllocationID.NotFound" {
-                        log.Print("EIP: Element not found.", err)
-                        found = false
-                        continue
-                    }
-                    if aerr.Code() != "AuthFailure" && aerr.Code() != "InvalidAllocationID.NotFound" {
-                        log.Fatal("EIP: Releasing EIP error: ", err)
-                    }
-                } else {
-                    log.Fatal("EIP: There was an error: ", err.Error())
-                }
-            }
-            log.Println("EIP: Releasing EIP. Retry: ", retry)
-            time.Sleep(5 * time.Second)
-        }
-    }
-}
-
-// removes resource groups using AWS session based on name
-func removeResourceGroup(session *session.Session, rgToRemoveName string) {
-
-	rgClient := resourcegroups.New(session)
-
-	log.Println("Resource Group: Removing resource group: ", rgToRemoveName)
-	rgDelInp := resourcegroups.DeleteGroupInput{
-		GroupName: aws.String(rgToRemoveName),
-	}
-	rgDelOut, rgDelErr := rgClient.DeleteGroup(&rgDelInp)
-	if rgDelErr != nil {
-		if aerr, ok := rgDelErr.(awserr.Error); ok {
-			if aerr.Code() == "NotFoundException" {
-				log.Println("Resource Group: Resource group not found. ")
-			} else {
-				log.Fatal("Resource Group: Deleting resource group error: ", rgDelErr)
-			}
-		} else {
-			log.Fatal("Resource Group: There was an error: ", rgDelErr.Error())
-		}
-
-	} else {
-		log.Println("Resource Group: Deleting resource group: ", rgDelOut)
-	}
-
-}